@@ -3,110 +3,84 @@ package ai
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
-	"time"
+	"sync"
 	"webhook_tg_bot/internal/config"
-
-	"github.com/sashabaranov/go-openai"
 )
 
-// AIProvider интерфейс для работы с AI
+// AIProvider интерфейс для работы с AI.
+// Таймаут запроса задается через ctx
 type AIProvider interface {
-	GenerateSummary(content, title, authorRole, category string) (string, error)
-}
-
-// OpenAIProvider реализация для OpenAI
-type OpenAIProvider struct {
-	client *openai.Client
-	model  string
-}
-
-// NewProvider создает провайдер AI в зависимости от конфигурации
-func NewProvider(cfg *config.Config) (AIProvider, error) {
-	if cfg.OpenAIAPIKey == "" {
-		return nil, fmt.Errorf("OpenAI API key is required")
-	}
-	return &OpenAIProvider{
-		client: openai.NewClient(cfg.OpenAIAPIKey),
-		model:  cfg.OpenAIModel,
-	}, nil
+	GenerateSummary(ctx context.Context, req *SummaryRequest) (string, error)
 }
 
-// GenerateSummary генерирует краткое резюме с помощью OpenAI
-func (p *OpenAIProvider) GenerateSummary(content, title, authorRole, category string) (string, error) {
-	// Очищаем содержимое от HTML тегов для лучшего анализа
-	cleanContent := strings.ReplaceAll(content, "<p>", "")
-	cleanContent = strings.ReplaceAll(cleanContent, "</p>", "")
-	cleanContent = strings.ReplaceAll(cleanContent, "<br>", " ")
-	cleanContent = strings.TrimSpace(cleanContent)
-
-	prompt := fmt.Sprintf(`Ты - эксперт по анализу контента технических форумов. Создай краткое описание КОНКРЕТНОГО ПОСТА.
-
-КОНТЕКСТ:
-Тема форума: "%s"
-Категория: %s
-Роль автора: %s
-
-СОДЕРЖАНИЕ КОНКРЕТНОГО ПОСТА:
-%s
-
-ПРАВИЛА ОПИСАНИЯ ПОСТА:
-
-1. ОСМЫСЛЕННЫЙ КОНТЕНТ:
-   • Опиши что конкретно написал автор в этом посте (НЕ в теме в целом)
-   • Укажи суть сообщения в 1-2 предложениях
-   • НЕ описывай тему форума, а именно содержание поста
-   • Фокусируйся на том, что автор хотел сказать
+// Factory создает провайдер по конфигурации
+type Factory func(cfg *config.Config) (AIProvider, error)
 
-2. ТЕСТОВЫЙ/БЕССМЫСЛЕННЫЙ КОНТЕНТ:
-   • "Автор оставил тестовое сообщение"
-   • "Пост содержит бессмысленный набор символов"
-
-3. ВОПРОСЫ В ПОСТЕ:
-   • "Автор задает вопрос о..."
-   • "Пользователь просит помощи с..."
-
-4. ОТВЕТЫ/РЕШЕНИЯ В ПОСТЕ:
-   • "Автор предлагает решение..."
-   • "Пользователь объясняет как..."
+var (
+	registry      = make(map[string]Factory)
+	registryMutex sync.RWMutex
+)
 
-5. КОММЕНТАРИИ/МНЕНИЯ В ПОСТЕ:
-   • "Автор высказывает мнение о..."
-   • "Пользователь комментирует..."
+// Register регистрирует провайдер под именем, которое указывается в AI_PROVIDER
+func Register(name string, factory Factory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
 
-ВАЖНО:
-- Описывай именно СОДЕРЖАНИЕ ПОСТА, а не тему форума
-- Максимум 2 предложения на русском языке
-- Не упоминай название темы и роль автора в описании
-- Фокусируйся на том, что написано в самом сообщении
+	registry[name] = factory
+}
 
-Описание поста:`, title, category, authorRole, cleanContent)
+// Providers возвращает имена зарегистрированных провайдеров
+func Providers() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
-	resp, err := p.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: p.model,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: prompt,
-				},
-			},
-			MaxTokens:   100,
-			Temperature: 0.2,
-		},
-	)
+// NewProvider создает провайдер AI в зависимости от конфигурации.
+// Провайдеры из AI_PROVIDERS опрашиваются по очереди, а если все они не справились,
+// резюме составляется из первых предложений поста
+func NewProvider(cfg *config.Config) (AIProvider, error) {
+	// Без AI анонсы отправляются без резюме
+	if len(cfg.AIProviders) == 1 && cfg.AIProviders[0].Name == "none" {
+		return NoneProvider{}, nil
+	}
 
+	prompts, err := LoadPrompts(cfg)
 	if err != nil {
-		return "", fmt.Errorf("OpenAI API error: %v", err)
+		return nil, err
 	}
 
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response from OpenAI")
+	chain := &ChainProvider{prompts: prompts}
+	for _, providerConfig := range cfg.AIProviders {
+		provider, err := newNamedProvider(providerConfig.Name, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("AI provider %s: %v", providerConfig.Name, err)
+		}
+		chain.links = append(chain.links, chainLink{
+			name:     providerConfig.Name,
+			provider: provider,
+			timeout:  providerConfig.Timeout,
+		})
 	}
+	return chain, nil
+}
 
-	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+// newNamedProvider создает зарегистрированный провайдер по имени
+func newNamedProvider(name string, cfg *config.Config) (AIProvider, error) {
+	registryMutex.RLock()
+	factory, exists := registry[name]
+	registryMutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unknown AI provider %q (available: %s)", name, strings.Join(Providers(), ", "))
+	}
+	return factory(cfg)
 }
@@ -0,0 +1,122 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"webhook_tg_bot/internal/config"
+)
+
+const (
+	anthropicAPIURL  = "https://api.anthropic.com/v1/messages"
+	anthropicVersion = "2023-06-01"
+)
+
+func init() {
+	Register("anthropic", newAnthropicProvider)
+}
+
+// AnthropicProvider реализация для Anthropic Messages API
+type AnthropicProvider struct {
+	apiKey string
+	model  string
+	url    string
+	client *http.Client
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float64            `json:"temperature"`
+	Messages    []anthropicMessage `json:"messages"`
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func newAnthropicProvider(cfg *config.Config) (AIProvider, error) {
+	if cfg.AnthropicAPIKey == "" {
+		return nil, fmt.Errorf("Anthropic API key is required")
+	}
+
+	return &AnthropicProvider{
+		apiKey: cfg.AnthropicAPIKey,
+		model:  cfg.AnthropicModel,
+		url:    anthropicAPIURL,
+		client: &http.Client{},
+	}, nil
+}
+
+// GenerateSummary генерирует краткое резюме с помощью Anthropic
+func (p *AnthropicProvider) GenerateSummary(ctx context.Context, req *SummaryRequest) (string, error) {
+	body, err := json.Marshal(anthropicRequest{
+		Model:       p.model,
+		MaxTokens:   200,
+		Temperature: 0.2,
+		Messages: []anthropicMessage{
+			{Role: "user", Content: req.Prompt},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode Anthropic request: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create Anthropic request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("Anthropic API error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Anthropic response: %v", err)
+	}
+
+	var result anthropicResponse
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return "", fmt.Errorf("failed to decode Anthropic response (%s): %v", resp.Status, err)
+	}
+	if result.Error != nil {
+		return "", fmt.Errorf("Anthropic API error: %s: %s", result.Error.Type, result.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Anthropic API returned %s", resp.Status)
+	}
+
+	var text strings.Builder
+	for _, block := range result.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no response from Anthropic")
+	}
+
+	return strings.TrimSpace(text.String()), nil
+}
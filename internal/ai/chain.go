@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"webhook_tg_bot/internal/metrics"
+)
+
+// ChainProvider опрашивает провайдеров по очереди, пока один из них не вернет резюме.
+// Если все провайдеры недоступны, резюме составляется из первых предложений поста
+type ChainProvider struct {
+	links   []chainLink
+	prompts *Prompts
+}
+
+type chainLink struct {
+	name     string
+	provider AIProvider
+	timeout  time.Duration
+}
+
+// GenerateSummary возвращает резюме первого ответившего провайдера
+func (c *ChainProvider) GenerateSummary(ctx context.Context, req *SummaryRequest) (string, error) {
+	var errs []error
+	if err := c.renderPrompt(req); err != nil {
+		// Без запроса обращаться к провайдерам бессмысленно
+		log.Printf("Failed to render AI prompt, using fallback summary: %v", err)
+		errs = append(errs, err)
+	} else {
+		for _, link := range c.links {
+			summary, err := c.generate(ctx, link, req)
+			if err == nil {
+				metrics.Inc("ai_summary_" + link.name)
+				return summary, nil
+			}
+
+			log.Printf("AI provider %s failed, trying next: %v", link.name, err)
+			metrics.Inc("ai_failure_" + link.name)
+			errs = append(errs, fmt.Errorf("%s: %w", link.name, err))
+		}
+	}
+
+	summary := extractiveSummary(req.Content, req.Language)
+	if summary == "" {
+		return "", errors.Join(errs...)
+	}
+
+	metrics.Inc("ai_summary_fallback")
+	return summary, nil
+}
+
+// renderPrompt заполняет текст запроса по выбранному шаблону
+func (c *ChainProvider) renderPrompt(req *SummaryRequest) error {
+	if req.Prompt != "" {
+		return nil
+	}
+	prompt, err := c.prompts.Render(req)
+	if err != nil {
+		return err
+	}
+	req.Prompt = prompt
+	return nil
+}
+
+func (c *ChainProvider) generate(ctx context.Context, link chainLink, req *SummaryRequest) (string, error) {
+	if link.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, link.timeout)
+		defer cancel()
+	}
+
+	summary, err := link.provider.GenerateSummary(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return summary, nil
+}
@@ -0,0 +1,132 @@
+package ai
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/i18n"
+)
+
+const (
+	localMaxSentences = 2
+	localMaxLength    = 300
+	// Вопрос ищем только в начале поста - дальше обычно идут подробности
+	localQuestionWindow = 3
+)
+
+var (
+	quoteBlockPattern  = regexp.MustCompile(`(?is)\[quote[^\]]*\].*?\[/quote\]|<aside[^>]*class="[^"]*quote[^"]*"[^>]*>.*?</aside>|<blockquote[^>]*>.*?</blockquote>`)
+	codeBlockPattern   = regexp.MustCompile("(?is)```.*?```|~~~.*?~~~|<pre[^>]*>.*?</pre>|\\[code\\].*?\\[/code\\]")
+	inlineCodePattern  = regexp.MustCompile("`([^`\n]*)`")
+	imagePattern       = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)|(?i)<img[^>]*>`)
+	markdownLink       = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	bareURLPattern     = regexp.MustCompile(`https?://\S+`)
+	htmlTagPattern     = regexp.MustCompile(`<[^>]*>`)
+	markdownMarkup     = regexp.MustCompile(`(?m)^\s*(#{1,6}\s+|[-*+]\s+|\d+[.)]\s+)|\*{1,3}|~~`)
+	whitespacePattern  = regexp.MustCompile(`\s+`)
+	sentencePattern    = regexp.MustCompile(`\S.*?(?:[.!?…]+(?:\s|$)|$)`)
+	greetingPattern    = regexp.MustCompile(`(?i)^(всем\s+)?(привет|здравствуйте|добрый\s+(день|вечер)|доброе\s+утро|hi|hello|hey)(\s+(всем|все|all|everyone|друзья|коллеги))?[\s!.,)]*$`)
+	blockBoundaryTags  = regexp.MustCompile(`(?i)</?(p|br|li|h[1-6]|div)[^>]*>`)
+	lineQuotePattern   = regexp.MustCompile(`(?m)^\s*>.*$`)
+	emptyParensPattern = regexp.MustCompile(`\(\s*\)`)
+)
+
+func init() {
+	Register("local", func(cfg *config.Config) (AIProvider, error) {
+		return LocalProvider{}, nil
+	})
+}
+
+// LocalProvider составляет описание поста эвристиками, без обращения к внешним сервисам
+type LocalProvider struct{}
+
+// GenerateSummary возвращает одно-два первых осмысленных предложения поста
+func (LocalProvider) GenerateSummary(ctx context.Context, req *SummaryRequest) (string, error) {
+	return extractiveSummary(req.Content, req.Language), nil
+}
+
+// extractiveSummary убирает из поста цитаты, код и ссылки и выбирает первые предложения.
+// Если автор в начале поста задает вопрос, описание строится вокруг вопроса
+func extractiveSummary(content, locale string) string {
+	sentences := meaningfulSentences(plainText(content))
+	if len(sentences) == 0 {
+		return ""
+	}
+
+	for i, sentence := range sentences {
+		if i >= localQuestionWindow {
+			break
+		}
+		if strings.HasSuffix(sentence, "?") {
+			return truncateRunes(i18n.T(locale, "summary.question", sentence), localMaxLength)
+		}
+	}
+
+	if len(sentences) > localMaxSentences {
+		sentences = sentences[:localMaxSentences]
+	}
+
+	summary := sentences[0]
+	for _, sentence := range sentences[1:] {
+		if utf8.RuneCountInString(summary)+utf8.RuneCountInString(sentence)+1 > localMaxLength {
+			break
+		}
+		// Строки заголовков и списков не заканчиваются точкой
+		if last, _ := utf8.DecodeLastRuneInString(summary); !strings.ContainsRune(".!?…", last) {
+			summary += "."
+		}
+		summary += " " + sentence
+	}
+
+	return truncateRunes(summary, localMaxLength)
+}
+
+// plainText превращает Raw (markdown) или Cooked (HTML) в простой текст
+func plainText(content string) string {
+	text := quoteBlockPattern.ReplaceAllString(content, " ")
+	text = codeBlockPattern.ReplaceAllString(text, " ")
+	text = lineQuotePattern.ReplaceAllString(text, " ")
+	text = inlineCodePattern.ReplaceAllString(text, "$1")
+	text = imagePattern.ReplaceAllString(text, " ")
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = blockBoundaryTags.ReplaceAllString(text, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, " ")
+	text = html.UnescapeString(text)
+	text = bareURLPattern.ReplaceAllString(text, " ")
+	text = emptyParensPattern.ReplaceAllString(text, " ")
+	text = markdownMarkup.ReplaceAllString(text, "")
+	return text
+}
+
+// meaningfulSentences делит текст на предложения, пропуская приветствия и обрывки
+func meaningfulSentences(text string) []string {
+	var sentences []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		paragraph = strings.TrimSpace(whitespacePattern.ReplaceAllString(paragraph, " "))
+		if paragraph == "" {
+			continue
+		}
+
+		for _, sentence := range sentencePattern.FindAllString(paragraph, -1) {
+			sentence = strings.TrimSpace(sentence)
+			if utf8.RuneCountInString(sentence) < 3 || greetingPattern.MatchString(sentence) {
+				continue
+			}
+			// Абзац без точки в конце (заголовок, строка списка) считаем отдельным предложением
+			sentences = append(sentences, sentence)
+		}
+	}
+	return sentences
+}
+
+// truncateRunes обрезает строку до limit символов, добавляя многоточие
+func truncateRunes(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
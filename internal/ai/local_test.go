@@ -0,0 +1,45 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestExtractiveSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		locale  string
+		want    string
+	}{
+		{"empty", "", "en", ""},
+		{"first two sentences", "First sentence. Second one! Third sentence.", "en", "First sentence. Second one!"},
+		{"greeting skipped", "Hello everyone!\nThe build fails on CI. Logs are attached.", "en", "The build fails on CI. Logs are attached."},
+		{"question in english", "How do I configure webhooks? I tried everything.", "en", "The author asks: How do I configure webhooks?"},
+		{"question in russian", "Как настроить вебхуки? Пробовал все.", "ru", "Автор задает вопрос: Как настроить вебхуки?"},
+		{"quotes and code removed", "[quote=\"bob\"]old text.[/quote]\nNew release is out.\n```\ncode here.\n```", "en", "New release is out."},
+		{"links keep their text", "See [the docs](https://example.com/docs) for details. https://example.com", "en", "See the docs for details."},
+		{"heading gets a period", "# Release notes\nVersion 2 is available.", "en", "Release notes. Version 2 is available."},
+		{"html paragraphs", "<p>First paragraph.</p><p>Second paragraph.</p>", "en", "First paragraph. Second paragraph."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractiveSummary(tt.content, tt.locale); got != tt.want {
+				t.Errorf("extractiveSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractiveSummaryLength(t *testing.T) {
+	content := strings.Repeat("word ", 200) + "end."
+	summary := extractiveSummary(content, "en")
+	if utf8.RuneCountInString(summary) > localMaxLength {
+		t.Errorf("summary has %d runes, want at most %d", utf8.RuneCountInString(summary), localMaxLength)
+	}
+	if !strings.HasSuffix(summary, "…") {
+		t.Errorf("truncated summary %q should end with an ellipsis", summary)
+	}
+}
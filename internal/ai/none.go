@@ -0,0 +1,20 @@
+package ai
+
+import (
+	"context"
+	"webhook_tg_bot/internal/config"
+)
+
+func init() {
+	Register("none", func(cfg *config.Config) (AIProvider, error) {
+		return NoneProvider{}, nil
+	})
+}
+
+// NoneProvider отключает генерацию резюме: анонсы отправляются без описания
+type NoneProvider struct{}
+
+// GenerateSummary всегда возвращает пустое резюме
+func (NoneProvider) GenerateSummary(ctx context.Context, req *SummaryRequest) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,80 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"webhook_tg_bot/internal/config"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func init() {
+	Register("openai", newOpenAIProvider)
+	Register("openai-compatible", newOpenAICompatibleProvider)
+}
+
+// OpenAIProvider реализация для OpenAI и совместимых API (Ollama, LM Studio, vLLM, OpenRouter)
+type OpenAIProvider struct {
+	client *openai.Client
+	model  string
+}
+
+func newOpenAIProvider(cfg *config.Config) (AIProvider, error) {
+	if cfg.OpenAIAPIKey == "" {
+		return nil, fmt.Errorf("OpenAI API key is required")
+	}
+
+	return &OpenAIProvider{
+		client: openai.NewClient(cfg.OpenAIAPIKey),
+		model:  cfg.OpenAIModel,
+	}, nil
+}
+
+func newOpenAICompatibleProvider(cfg *config.Config) (AIProvider, error) {
+	if cfg.AIBaseURL == "" {
+		return nil, fmt.Errorf("AI_BASE_URL is required for the openai-compatible provider")
+	}
+	if cfg.AIModel == "" {
+		return nil, fmt.Errorf("AI_MODEL is required for the openai-compatible provider")
+	}
+
+	// Свои endpoint, ключ и модель: ключ OpenAI не уходит на сторонний сервер,
+	// а openai и openai-compatible можно использовать в одной цепочке.
+	// Локальным серверам ключ обычно не нужен
+	clientConfig := openai.DefaultConfig(cfg.AIAPIKey)
+	clientConfig.BaseURL = cfg.AIBaseURL
+
+	return &OpenAIProvider{
+		client: openai.NewClientWithConfig(clientConfig),
+		model:  cfg.AIModel,
+	}, nil
+}
+
+// GenerateSummary генерирует краткое резюме с помощью OpenAI
+func (p *OpenAIProvider) GenerateSummary(ctx context.Context, req *SummaryRequest) (string, error) {
+	resp, err := p.client.CreateChatCompletion(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: p.model,
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: req.Prompt,
+				},
+			},
+			MaxTokens:   100,
+			Temperature: 0.2,
+		},
+	)
+
+	if err != nil {
+		return "", fmt.Errorf("OpenAI API error: %v", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response from OpenAI")
+	}
+
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,195 @@
+package ai
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/i18n"
+)
+
+// DefaultPrompt имя встроенного шаблона запроса
+const DefaultPrompt = "default"
+
+//go:embed prompts/*.tmpl
+var embeddedPrompts embed.FS
+
+// SummaryRequest данные поста для генерации резюме. Поля доступны в шаблонах запроса
+type SummaryRequest struct {
+	Title      string
+	Category   string
+	CategoryID int
+	AuthorRole string
+	Content    string
+	Tags       []string
+	Language   string // код языка резюме, например ru или en
+	Template   string // имя шаблона запроса; пусто - шаблон по умолчанию
+
+	// Prompt готовый текст запроса, заполняется из шаблона перед обращением к провайдерам
+	Prompt string
+}
+
+// Prompts набор шаблонов запроса к AI
+type Prompts struct {
+	templates   map[string]*template.Template
+	defaultName string
+}
+
+var promptFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// languageName возвращает название языка по коду: {{languageName .Language}} -> English
+	"languageName": func(locale string) string {
+		return i18n.T(locale, "language.name")
+	},
+}
+
+// LoadPrompts загружает встроенные шаблоны и файлы *.tmpl из PROMPTS_DIR (имя шаблона - имя файла).
+// Файл <имя>.<язык>.tmpl, например default.en.tmpl, используется вместо <имя>.tmpl для резюме на этом языке.
+// Файл <имя>.tmpl из PROMPTS_DIR заменяет встроенный шаблон вместе с его языковыми вариантами:
+// встроенный default.en.tmpl не перекроет свой default.tmpl, если рядом нет своего default.en.tmpl.
+// Каждый шаблон проверяется пробной подстановкой, а шаблоны из конфигурации - на существование,
+// чтобы ошибки обнаруживались при запуске, а не при первом анонсе
+func LoadPrompts(cfg *config.Config) (*Prompts, error) {
+	prompts := &Prompts{
+		templates:   make(map[string]*template.Template),
+		defaultName: cfg.PromptTemplate,
+	}
+
+	var errs []error
+	embedded, _ := embeddedPrompts.ReadDir("prompts")
+	for _, entry := range embedded {
+		data, err := embeddedPrompts.ReadFile("prompts/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if err := prompts.add(entry.Name(), string(data)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if cfg.PromptsDir != "" {
+		files, err := filepath.Glob(filepath.Join(cfg.PromptsDir, "*.tmpl"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list prompt templates: %v", err)
+		}
+		if len(files) == 0 {
+			errs = append(errs, fmt.Errorf("no *.tmpl files in %s", cfg.PromptsDir))
+		}
+		custom := make(map[string]bool, len(files))
+		for _, file := range files {
+			custom[strings.TrimSuffix(filepath.Base(file), ".tmpl")] = true
+		}
+		prompts.dropEmbeddedVariants(custom)
+
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to read %s: %v", file, err))
+				continue
+			}
+			if err := prompts.add(filepath.Base(file), string(data)); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	for _, name := range cfg.PromptNames() {
+		if _, exists := prompts.templates[name]; !exists {
+			errs = append(errs, fmt.Errorf("prompt template %q is not defined (available: %s)", name, strings.Join(prompts.Names(), ", ")))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid prompt templates: %w", errors.Join(errs...))
+	}
+	return prompts, nil
+}
+
+// add разбирает шаблон и проверяет его на тестовых данных. Файл из PROMPTS_DIR
+// с тем же именем заменяет встроенный шаблон
+func (p *Prompts) add(fileName, text string) error {
+	name := strings.TrimSuffix(fileName, ".tmpl")
+	tmpl, err := template.New(name).Funcs(promptFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return fmt.Errorf("template %s: %v", fileName, err)
+	}
+
+	sample := &SummaryRequest{
+		Title:      "Пример темы",
+		Category:   "Пример категории",
+		CategoryID: 1,
+		AuthorRole: "user",
+		Content:    "Пример поста",
+		Tags:       []string{"example"},
+		Language:   "ru",
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, sample); err != nil {
+		return fmt.Errorf("template %s: %v", fileName, err)
+	}
+
+	p.templates[name] = tmpl
+	return nil
+}
+
+// dropEmbeddedVariants убирает встроенные языковые варианты шаблонов, которые заменены
+// файлами из PROMPTS_DIR, иначе встроенный вариант выигрывал бы у своего шаблона
+func (p *Prompts) dropEmbeddedVariants(custom map[string]bool) {
+	for name := range p.templates {
+		base, _, found := strings.Cut(name, ".")
+		if found && custom[base] && !custom[name] {
+			log.Printf("Prompt template %s from PROMPTS_DIR replaces embedded %s", base, name)
+			delete(p.templates, name)
+		}
+	}
+}
+
+// Names возвращает имена загруженных шаблонов
+func (p *Prompts) Names() []string {
+	names := make([]string, 0, len(p.templates))
+	for name := range p.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render формирует текст запроса по шаблону из req.Template, предпочитая вариант для req.Language
+func (p *Prompts) Render(req *SummaryRequest) (string, error) {
+	name := req.Template
+	if name == "" {
+		name = p.defaultName
+	}
+	tmpl, exists := p.templates[name+"."+req.Language]
+	if !exists {
+		tmpl, exists = p.templates[name]
+	}
+	if !exists {
+		return "", fmt.Errorf("unknown prompt template %q", name)
+	}
+
+	data := *req
+	data.Content = cleanContent(req.Content)
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, &data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %v", name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// cleanContent очищает содержимое от HTML тегов для лучшего анализа
+func cleanContent(content string) string {
+	cleanContent := strings.ReplaceAll(content, "<p>", "")
+	cleanContent = strings.ReplaceAll(cleanContent, "</p>", "")
+	cleanContent = strings.ReplaceAll(cleanContent, "<br>", " ")
+	return strings.TrimSpace(cleanContent)
+}
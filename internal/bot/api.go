@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Библиотека telegram-bot-api v5.5.1 не знает о message_thread_id,
+// поэтому сообщения отправляются через MakeRequest с собственными параметрами
+
+// messageParams собирает параметры sendMessage для исходящего сообщения
+func messageParams(msg *models.OutgoingMessage) tgbotapi.Params {
+	params := make(tgbotapi.Params)
+	params.AddFirstValid("chat_id", msg.ChatID)
+	params["text"] = msg.Text
+	params.AddNonEmpty("parse_mode", msg.ParseMode)
+	addThreadParams(params, msg)
+	addKeyboardParams(params, msg)
+	return params
+}
+
+// addKeyboardParams добавляет inline-кнопки сообщения
+func addKeyboardParams(params tgbotapi.Params, msg *models.OutgoingMessage) {
+	if markup := inlineKeyboard(msg.Keyboard); markup != nil {
+		// Ошибка сериализации невозможна для структуры из строк
+		_ = params.AddInterface("reply_markup", markup)
+	}
+}
+
+// addThreadParams размещает сообщение в thread в зависимости от режима:
+// в теме форума (message_thread_id) или ответом на якорное сообщение (reply_to_message_id)
+func addThreadParams(params tgbotapi.Params, msg *models.OutgoingMessage) {
+	replyTo := msg.ReplyToMessageID
+
+	if msg.ThreadID != 0 {
+		if msg.ThreadMode == config.ThreadModeReply {
+			// Ответ на конкретное сообщение (например, на анонс темы) важнее якоря thread'а
+			if replyTo == 0 {
+				replyTo = msg.ThreadID
+			}
+		} else {
+			params.AddNonZero("message_thread_id", msg.ThreadID)
+		}
+	}
+
+	if replyTo != 0 {
+		params.AddNonZero("reply_to_message_id", replyTo)
+		// Не теряем сообщение, если якорь или анонс удалили
+		params.AddBool("allow_sending_without_reply", true)
+	}
+}
+
+// requestMessage вызывает метод Bot API, возвращающий Message
+func (tb *TelegramBot) requestMessage(endpoint string, params tgbotapi.Params) (tgbotapi.Message, error) {
+	var message tgbotapi.Message
+
+	resp, err := tb.bot.MakeRequest(endpoint, params)
+	if err != nil {
+		return message, err
+	}
+
+	if err := json.Unmarshal(resp.Result, &message); err != nil {
+		return message, fmt.Errorf("failed to decode %s response: %v", endpoint, err)
+	}
+	return message, nil
+}
@@ -1,22 +1,51 @@
 package bot
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"html/template"
 	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 	"webhook_tg_bot/internal/ai"
 	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/discourse"
+	"webhook_tg_bot/internal/i18n"
+	"webhook_tg_bot/internal/metrics"
 	"webhook_tg_bot/internal/models"
+	"webhook_tg_bot/internal/storage"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
 type TelegramBot struct {
-	bot    *tgbotapi.BotAPI
-	config *config.Config
-	ai     ai.AIProvider
+	bot       *tgbotapi.BotAPI
+	config    *config.Config
+	ai        ai.AIProvider
+	storage   storage.Storage
+	templates *Templates
+
+	commands    map[string]commandHandler
+	topicLoader TopicLoader
+	startedAt   time.Time
+
+	imageClient *http.Client // скачивает картинки для анонсов (newImageClient)
+
+	updateSlots chan struct{}  // ограничивает число одновременно обрабатываемых обновлений
+	updatesWG   sync.WaitGroup // обработчики обновлений, которых ждет StopUpdates
+
+	userCommands       map[string]commandHandler
+	notifications      chan *subscriptionJob
+	discourse          *discourse.Client
+	categories         []models.Category // кеш категорий форума для меню подписки
+	categoriesLoadedAt time.Time
+	categoriesMutex    sync.Mutex
 }
 
-func New(cfg *config.Config) (*TelegramBot, error) {
+func New(cfg *config.Config, store storage.Storage) (*TelegramBot, error) {
 	bot, err := tgbotapi.NewBotAPI(cfg.TelegramBotToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create telegram bot: %v", err)
@@ -28,82 +57,455 @@ func New(cfg *config.Config) (*TelegramBot, error) {
 		return nil, fmt.Errorf("failed to create AI provider: %v", err)
 	}
 
+	// Шаблоны сообщений проверяются при запуске
+	templates, err := LoadTemplates(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	log.Printf("Authorized on account %s", bot.Self.UserName)
+	log.Printf("Using AI providers: %s", aiProviderNames(cfg.AIProviders))
 
-	return &TelegramBot{
-		bot:    bot,
-		config: cfg,
-		ai:     aiProvider,
-	}, nil
+	tb := &TelegramBot{
+		bot:       bot,
+		config:    cfg,
+		ai:        aiProvider,
+		storage:   store,
+		templates: templates,
+		startedAt: time.Now(),
+		discourse: discourse.NewClient(cfg),
+
+		updateSlots: make(chan struct{}, maxConcurrentUpdates),
+	}
+	tb.imageClient = tb.newImageClient()
+	tb.setupCommands()
+	if cfg.Subscriptions {
+		tb.setupUserCommands()
+		tb.startNotifier()
+	}
+	return tb, nil
 }
 
+// SendCompleteNotification отправляет анонс темы во все подходящие чаты и личным подписчикам.
+// Каждый чат обрабатывается независимо: ошибка в одном не мешает остальным
 func (tb *TelegramBot) SendCompleteNotification(processed *models.ProcessedWebhook, isPremium bool) error {
-	// Генерируем краткое резюме с помощью AI
-	summary, err := tb.ai.GenerateSummary(processed.Content, processed.TopicTitle, processed.AuthorRole, processed.Category)
+	// Резюме генерируется один раз на каждую пару шаблона запроса и языка
+	summaries := make(map[string]string)
+
+	// Тема из неотслеживаемой категории приходит только ради правил наблюдения
+	monitored := tb.config.ShouldMonitorCategory(processed.CategoryID)
+
+	var destinations []*config.Destination
+	if monitored {
+		destinations = tb.config.MatchingDestinations(processed)
+		if len(destinations) == 0 {
+			log.Printf("Skipping topic %d - no destination accepts category %d", processed.TopicID, processed.CategoryID)
+		}
+		destinations = tb.withoutMuted(processed, destinations)
+	}
+
+	// Подписчикам уходят только темы, которые анонсируются в чатах с учетом фильтров и /mute
+	public := len(destinations) > 0
+
+	// Правила наблюдения отправляют тему в свои чаты независимо от категории и /mute
+	destinations = append(destinations, tb.watchDestinations(processed, destinations)...)
+	if len(destinations) > 0 {
+		if err := tb.announce(processed, isPremium, destinations, summaries); err != nil {
+			return err
+		}
+	}
+
+	if public {
+		tb.notifySubscribers(processed, isPremium, summaries)
+	}
+	return nil
+}
+
+// announce отправляет анонс в чаты destinations и запоминает отправленные сообщения.
+// Полученные резюме кешируются в summaries
+func (tb *TelegramBot) announce(processed *models.ProcessedWebhook, isPremium bool, destinations []*config.Destination, summaries map[string]string) error {
+	var refs []models.MessageRef
+	var errs []error
+	for _, destination := range destinations {
+		announced := tb.withSummary(processed, destination, summaries)
+		ref, err := tb.sendAnnouncement(destination, announced, isPremium)
+		if err != nil {
+			log.Printf("Failed to announce topic %d in %s: %v", processed.TopicID, destination.Name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", destination.Name, err))
+			continue
+		}
+		if len(refs) == 0 {
+			processed.Summary = announced.Summary
+		}
+		refs = append(refs, *ref)
+		metrics.Inc("announcements_sent")
+	}
+
+	// Ни один чат не получил анонс - ошибка вернется серверу, и он повторит обработку события (retryEvent)
+	if len(refs) == 0 {
+		return errors.Join(errs...)
+	}
+
+	// Запоминаем сообщения, чтобы обновить анонс при редактировании темы
+	now := time.Now()
+	announcement := &models.Announcement{
+		TopicID:   processed.TopicID,
+		Processed: *processed,
+		IsPremium: isPremium,
+		Messages:  refs,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	// При повторной отправке (/resend) сохраняем анонсы в чатах, куда тема не переотправлялась
+	if previous, err := tb.storage.GetAnnouncement(processed.TopicID); err == nil && previous != nil {
+		announcement.CreatedAt = previous.CreatedAt
+		for _, ref := range previous.Messages {
+			if !containsDestination(destinations, ref.Destination) {
+				announcement.Messages = append(announcement.Messages, ref)
+			}
+		}
+	}
+	if err := tb.storage.SaveAnnouncement(announcement); err != nil {
+		log.Printf("Failed to save announcement for topic %d: %v", processed.TopicID, err)
+	}
+
+	// Недоставленные анонсы уже сохранены в dead letters и могут быть отправлены повторно
+	return nil
+}
+
+// sendAnnouncement отправляет анонс в один чат
+func (tb *TelegramBot) sendAnnouncement(destination *config.Destination, processed *models.ProcessedWebhook, isPremium bool) (*models.MessageRef, error) {
+	msg, err := tb.announcementMessage(destination, processed, isPremium)
 	if err != nil {
-		log.Printf("Failed to generate AI summary: %v", err)
-		summary = "Не удалось сгенерировать резюме"
+		return nil, err
 	}
 
-	// Определяем префикс для роли автора
-	var rolePrefix string
-	switch processed.AuthorRole {
-	case "admin":
-		rolePrefix = "👑 "
-	case "moderator":
-		rolePrefix = "🛡️ "
-	case "staff":
-		rolePrefix = "⭐ "
-	case "leader":
-		rolePrefix = "🔥 "
-	default:
-		rolePrefix = ""
-	}
-
-	// Формируем сообщение по новому формату
-	message := fmt.Sprintf("👤 %s<b>%s</b> создал новый пост: <b>%s</b>\n\n"+
-		"📋 %s\n\n"+
-		"🔗 <a href=\"%s\">Ссылка на тему</a>\n\n"+
-		"🏷 Теги: %s",
-		rolePrefix,
-		processed.Author,
-		processed.TopicTitle,
-		summary,
-		processed.URL,
-		formatTags(processed.Tags))
-
-	// Добавляем информацию о платности, если нужно
-	if isPremium {
-		message += "\n\n💎 <b>Данный раздел доступен только по подписке.</b>\n" +
-			"Оформить VIP можно в тг-боте: @gig_combot"
+	// Анонс с картинкой уходит фотографией; при любой проблеме с фото отправляем текст
+	if destination.Photos && processed.ImageURL != "" {
+		if ref, ok := tb.sendPhotoAnnouncement(destination, processed, isPremium, msg); ok {
+			return ref, nil
+		}
+	}
+
+	messageID, err := tb.deliver(&models.DeadLetter{
+		TopicID:     processed.TopicID,
+		Message:     *msg,
+		Destination: destination.Name,
+		Processed:   processed,
+		IsPremium:   isPremium,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MessageRef{
+		Destination: destination.Name,
+		ChatID:      msg.ChatID,
+		ThreadID:    msg.ThreadID,
+		ThreadMode:  msg.ThreadMode,
+		MessageID:   messageID,
+	}, nil
+}
+
+// announcementMessage формирует текстовый анонс для чата; thread определяется по правилам маршрутизации
+func (tb *TelegramBot) announcementMessage(destination *config.Destination, processed *models.ProcessedWebhook, isPremium bool) (*models.OutgoingMessage, error) {
+	route := destination.ResolveRoute(processed)
+
+	text, err := tb.formatAnnouncement(destination, processed, isPremium)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OutgoingMessage{
+		ChatID:     route.ChatID,
+		ThreadID:   route.Thread.ID,
+		ThreadMode: route.Thread.Mode,
+		Text:       text,
+		ParseMode:  "HTML",
+		Keyboard:   tb.buildKeyboard(destination, processed, isPremium),
+	}, nil
+}
+
+// SendReplyNotification отправляет ответ в теме ответом на анонс этой темы
+func (tb *TelegramBot) SendReplyNotification(processed *models.ProcessedWebhook, announcement *models.Announcement, isAccepted bool) error {
+	summaries := make(map[string]string)
+
+	var errs []error
+	for _, ref := range announcement.Messages {
+		destination := tb.config.GetDestination(ref.Destination)
+		text, err := tb.formatReply(destination, tb.withSummary(processed, destination, summaries), isAccepted)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		msg := &models.OutgoingMessage{
+			ChatID:           ref.ChatID,
+			ThreadID:         ref.ThreadID,
+			ThreadMode:       ref.ThreadMode,
+			ReplyToMessageID: ref.MessageID,
+			Text:             text,
+			ParseMode:        "HTML",
+		}
+		if _, err := tb.deliver(&models.DeadLetter{TopicID: processed.TopicID, Message: *msg}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// EditAnnouncement заново генерирует резюме и обновляет ранее отправленный анонс
+func (tb *TelegramBot) EditAnnouncement(announcement *models.Announcement) error {
+	processed := &announcement.Processed
+	summaries := make(map[string]string)
+
+	var summary string
+	var errs []error
+	for i := range announcement.Messages {
+		ref := &announcement.Messages[i]
+		destination := tb.config.GetDestination(ref.Destination)
+		announced := tb.withSummary(processed, destination, summaries)
+		if summary == "" {
+			summary = announced.Summary
+		}
+
+		// Неудавшаяся правка помечается, чтобы следующее событие правки темы повторило ее,
+		// даже если содержимое темы с тех пор не изменилось
+		err := tb.editAnnouncementMessage(*ref, destination, announced, announcement.IsPremium)
+		ref.Outdated = err != nil && !isNotModified(err)
+		if ref.Outdated {
+			errs = append(errs, fmt.Errorf("failed to edit message %d in chat %d: %v", ref.MessageID, ref.ChatID, err))
+		}
 	}
 
-	// Определяем thread ID на основе категории
-	threadID := tb.config.GetThreadIDForCategory(processed.CategoryID)
+	processed.Summary = summary
+	announcement.UpdatedAt = time.Now()
+	if err := tb.storage.SaveAnnouncement(announcement); err != nil {
+		log.Printf("Failed to save announcement for topic %d: %v", announcement.TopicID, err)
+	}
 
-	return tb.sendMessage(message, threadID)
+	return errors.Join(errs...)
 }
 
-func (tb *TelegramBot) sendMessage(text string, threadID int) error {
-	msg := tgbotapi.NewMessage(tb.config.TelegramChatID, text)
-	msg.ParseMode = "HTML"
+// RemoveAnnouncement удаляет анонс темы из Telegram или заменяет его заглушкой
+// (ANNOUNCEMENT_REMOVAL_MODE). Если удалить сообщение нельзя (например, оно старше 48 часов),
+// анонс заменяется заглушкой
+func (tb *TelegramBot) RemoveAnnouncement(announcement *models.Announcement) error {
+	var errs []error
+	for _, ref := range announcement.Messages {
+		stub := i18n.T(tb.localeFor(tb.config.GetDestination(ref.Destination)), "announcement.removed", announcement.Processed.TopicTitle)
+
+		if tb.config.RemovalMode == "delete" {
+			_, err := tb.bot.Request(tgbotapi.NewDeleteMessage(ref.ChatID, ref.MessageID))
+			if err == nil {
+				continue
+			}
+			log.Printf("Failed to delete message %d in chat %d, replacing with stub: %v", ref.MessageID, ref.ChatID, err)
+		}
+
+		var err error
+		if ref.Kind == models.MessageKindPhoto {
+			err = tb.editCaption(ref.ChatID, ref.MessageID, stub, "", nil)
+		} else {
+			_, err = tb.bot.Request(tgbotapi.NewEditMessageText(ref.ChatID, ref.MessageID, stub))
+		}
+		if err != nil && !isNotModified(err) {
+			errs = append(errs, fmt.Errorf("failed to replace message %d in chat %d: %v", ref.MessageID, ref.ChatID, err))
+		}
+	}
+
+	if err := tb.storage.RemoveAnnouncement(announcement.TopicID); err != nil {
+		log.Printf("Failed to remove announcement for topic %d: %v", announcement.TopicID, err)
+	}
 
-	// Используем переданный thread ID, если он не равен 0
-	if threadID != 0 {
-		msg.ReplyToMessageID = threadID
+	return errors.Join(errs...)
+}
+
+// containsDestination проверяет, есть ли среди чатов чат с именем name
+func containsDestination(destinations []*config.Destination, name string) bool {
+	for _, destination := range destinations {
+		if destination.Name == name {
+			return true
+		}
 	}
+	return false
+}
 
-	_, err := tb.bot.Send(msg)
+// aiProviderNames перечисляет провайдеров цепочки для лога
+func aiProviderNames(providers []config.AIProviderConfig) string {
+	names := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		names = append(names, provider.Name)
+	}
+	return strings.Join(names, " -> ")
+}
+
+// editAnnouncementMessage обновляет текст анонса или подпись, если анонс отправлен фотографией.
+// Кнопки передаются заново: правка без reply_markup убирает их из сообщения
+func (tb *TelegramBot) editAnnouncementMessage(ref models.MessageRef, destination *config.Destination, processed *models.ProcessedWebhook, isPremium bool) error {
+	keyboard := tb.buildKeyboard(destination, processed, isPremium)
+
+	if ref.Kind == models.MessageKindPhoto {
+		caption, ok := tb.fitCaption(destination, processed, isPremium)
+		if !ok {
+			return fmt.Errorf("announcement does not fit into a photo caption")
+		}
+		return tb.editCaption(ref.ChatID, ref.MessageID, caption, "HTML", keyboard)
+	}
+
+	message, err := tb.formatAnnouncement(destination, processed, isPremium)
 	if err != nil {
-		return fmt.Errorf("failed to send telegram message: %v", err)
+		return err
 	}
+	return tb.editHTML(ref.ChatID, ref.MessageID, message, keyboard)
+}
 
-	return nil
+// editHTML заменяет текст сообщения, а если Telegram не разобрал разметку - повторяет правку простым текстом
+func (tb *TelegramBot) editHTML(chatID int64, messageID int, text string, keyboard [][]models.InlineButton) error {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ParseMode = "HTML"
+	edit.ReplyMarkup = inlineKeyboard(keyboard)
+
+	_, err := tb.bot.Request(edit)
+	if err != nil && isParseError(err) {
+		log.Printf("Telegram rejected HTML in message %d, editing as plain text: %v", messageID, err)
+		metrics.Inc("telegram_plain_fallback")
+		edit.Text = plainText(text)
+		edit.ParseMode = ""
+		_, err = tb.bot.Request(edit)
+	}
+	return err
+}
+
+// premiumNoticeFor возвращает уведомление о платности для чата, в который ушло сообщение.
+// destination может быть nil, если чат убрали из конфигурации после отправки
+func (tb *TelegramBot) premiumNoticeFor(destination *config.Destination, isPremium bool) string {
+	if destination != nil {
+		return destination.PremiumNoticeFor(isPremium)
+	}
+	if isPremium {
+		return i18n.T(tb.config.DefaultLocale, "premium.notice")
+	}
+	return ""
+}
+
+// localeFor возвращает язык чата (destination может быть nil)
+func (tb *TelegramBot) localeFor(destination *config.Destination) string {
+	if destination != nil && destination.Locale != "" {
+		return destination.Locale
+	}
+	return tb.config.DefaultLocale
+}
+
+// withSummary возвращает копию темы с резюме по шаблону запроса и на языке чата.
+// Резюме кешируется в summaries, чтобы не обращаться к AI повторно для того же шаблона и языка
+func (tb *TelegramBot) withSummary(processed *models.ProcessedWebhook, destination *config.Destination, summaries map[string]string) *models.ProcessedWebhook {
+	prompt := tb.config.PromptFor(destination, processed.CategoryID)
+	locale := tb.localeFor(destination)
+	key := prompt + "/" + locale
+
+	summary, exists := summaries[key]
+	if !exists {
+		summary = tb.generateSummary(processed, prompt, locale)
+		summaries[key] = summary
+	}
+
+	result := *processed
+	result.Summary = summary
+	return &result
+}
+
+// generateSummary генерирует краткое резюме с помощью AI по шаблону запроса prompt на языке locale.
+// Если резюме получить не удалось, анонс отправляется без него
+func (tb *TelegramBot) generateSummary(processed *models.ProcessedWebhook, prompt, locale string) string {
+	summary, err := tb.ai.GenerateSummary(context.Background(), &ai.SummaryRequest{
+		Title:      processed.TopicTitle,
+		Category:   categoryName(processed, locale),
+		CategoryID: processed.CategoryID,
+		AuthorRole: processed.AuthorRole,
+		Content:    processed.Content,
+		Tags:       processed.Tags,
+		Language:   locale,
+		Template:   prompt,
+	})
+	if err != nil {
+		log.Printf("Failed to generate AI summary: %v", err)
+		return ""
+	}
+	return summary
+}
+
+// formatAnnouncement формирует текст анонса темы по шаблону чата
+func (tb *TelegramBot) formatAnnouncement(destination *config.Destination, processed *models.ProcessedWebhook, isPremium bool) (string, error) {
+	data := tb.messageData(destination, processed)
+	data.IsPremium = isPremium
+	data.PremiumNotice = template.HTML(sanitizeHTML(tb.premiumNoticeFor(destination, isPremium)))
+	data.Highlight = tb.highlightFor(destination, processed)
+
+	return render(tb.templates.forDestination(destination).announcement, data)
+}
+
+// formatReply формирует текст уведомления об ответе в теме по шаблону чата
+func (tb *TelegramBot) formatReply(destination *config.Destination, processed *models.ProcessedWebhook, isAccepted bool) (string, error) {
+	data := tb.messageData(destination, processed)
+	data.IsAccepted = isAccepted
+
+	return render(tb.templates.forDestination(destination).reply, data)
+}
+
+// messageData собирает общие данные для шаблонов сообщений
+func (tb *TelegramBot) messageData(destination *config.Destination, processed *models.ProcessedWebhook) *MessageData {
+	locale := tb.localeFor(destination)
+	localized := *processed
+	localized.Category = categoryName(processed, locale)
+
+	return &MessageData{
+		ProcessedWebhook: &localized,
+		Summary:          template.HTML(sanitizeHTML(processed.Summary)),
+		CategoryName:     localized.Category,
+		RolePrefix:       rolePrefix(processed.AuthorRole),
+		Locale:           locale,
+	}
+}
+
+// categoryName возвращает название категории; если вебхук его не содержал, название
+// строится по ID на языке чата
+func categoryName(processed *models.ProcessedWebhook, locale string) string {
+	switch {
+	case processed.Category != "":
+		return processed.Category
+	case processed.CategoryID != 0:
+		return i18n.T(locale, "category.numbered", processed.CategoryID)
+	default:
+		return i18n.T(locale, "category.unknown")
+	}
+}
+
+// rolePrefix возвращает префикс для роли автора
+func rolePrefix(role string) string {
+	switch role {
+	case "admin":
+		return "👑 "
+	case "moderator":
+		return "🛡️ "
+	case "staff":
+		return "⭐ "
+	case "leader":
+		return "🔥 "
+	default:
+		return ""
+	}
+}
+
+// isNotModified проверяет, что Telegram отклонил правку, потому что текст не изменился
+func isNotModified(err error) bool {
+	return strings.Contains(err.Error(), "message is not modified")
 }
 
-func formatTags(tags []string) string {
+func formatTags(tags []string, locale string) string {
 	if len(tags) == 0 {
-		return "нет"
+		return i18n.T(locale, "announcement.no_tags")
 	}
 
 	result := ""
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/i18n"
+	"webhook_tg_bot/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// buildKeyboard собирает кнопки анонса по настройкам чата. Кнопка подписки
+// показывается только для платных разделов, кнопка категории - если известна категория
+func (tb *TelegramBot) buildKeyboard(destination *config.Destination, processed *models.ProcessedWebhook, isPremium bool) [][]models.InlineButton {
+	if destination == nil {
+		return nil
+	}
+	locale := tb.localeFor(destination)
+
+	var keyboard [][]models.InlineButton
+	for _, row := range destination.ButtonRows() {
+		var buttons []models.InlineButton
+		for _, button := range row {
+			url := tb.buttonURL(button, processed, isPremium)
+			if url == "" {
+				continue
+			}
+
+			text := button.Text
+			if text == "" {
+				text = i18n.T(locale, "button."+button.Type)
+			}
+			buttons = append(buttons, models.InlineButton{Text: text, URL: url})
+		}
+		if len(buttons) > 0 {
+			keyboard = append(keyboard, buttons)
+		}
+	}
+	return keyboard
+}
+
+// buttonURL возвращает ссылку кнопки или пустую строку, если кнопку показывать не нужно
+func (tb *TelegramBot) buttonURL(button config.Button, processed *models.ProcessedWebhook, isPremium bool) string {
+	switch button.Type {
+	case config.ButtonTopic:
+		return processed.URL
+	case config.ButtonCategory:
+		if processed.CategoryID == 0 {
+			return ""
+		}
+		baseURL := strings.TrimRight(tb.config.BaseURL, "/")
+		if processed.CategorySlug == "" {
+			return fmt.Sprintf("%s/c/%d", baseURL, processed.CategoryID)
+		}
+		return fmt.Sprintf("%s/c/%s/%d", baseURL, processed.CategorySlug, processed.CategoryID)
+	case config.ButtonReply:
+		// Discourse открывает конец темы, где находится кнопка ответа
+		return processed.URL + "/last"
+	case config.ButtonSubscribe:
+		if !isPremium {
+			return ""
+		}
+		return button.URL
+	default:
+		return button.URL
+	}
+}
+
+// inlineKeyboard переводит кнопки в разметку Bot API (nil, если кнопок нет)
+func inlineKeyboard(keyboard [][]models.InlineButton) *tgbotapi.InlineKeyboardMarkup {
+	if len(keyboard) == 0 {
+		return nil
+	}
+
+	markup := tgbotapi.InlineKeyboardMarkup{}
+	for _, row := range keyboard {
+		var buttons []tgbotapi.InlineKeyboardButton
+		for _, button := range row {
+			if button.URL == "" {
+				buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(button.Text, button.CallbackData))
+				continue
+			}
+			buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonURL(button.Text, button.URL))
+		}
+		markup.InlineKeyboard = append(markup.InlineKeyboard, buttons)
+	}
+	return &markup
+}
@@ -0,0 +1,435 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/i18n"
+	"webhook_tg_bot/internal/metrics"
+	"webhook_tg_bot/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// command команда администратора
+type command struct {
+	message *tgbotapi.Message
+	args    []string
+	scope   int64 // чат, которым управляет автор команды; 0 - все чаты
+	locale  string
+}
+
+// commandHandler выполняет команду и возвращает текст ответа (HTML)
+type commandHandler func(cmd *command) string
+
+// TopicLoader загружает тему, которая не анонсировалась, для команды /resend
+type TopicLoader func(topicID int) (*models.ProcessedWebhook, bool, error)
+
+// SetTopicLoader задает загрузку тем с форума для /resend
+func (tb *TelegramBot) SetTopicLoader(loader TopicLoader) {
+	tb.topicLoader = loader
+}
+
+// setupCommands регистрирует команды администратора
+func (tb *TelegramBot) setupCommands() {
+	tb.commands = map[string]commandHandler{
+		"help":   tb.cmdHelp,
+		"status": tb.cmdStatus,
+		"mute":   tb.cmdMute,
+		"unmute": tb.cmdUnmute,
+		"routes": tb.cmdRoutes,
+		"test":   tb.cmdTest,
+		"resend": tb.cmdResend,
+	}
+}
+
+// handleCommand проверяет права и выполняет команду
+func (tb *TelegramBot) handleCommand(message *tgbotapi.Message) {
+	name := strings.ToLower(message.Command())
+	locale := tb.commandLocale(message.Chat.ID)
+	args := strings.Fields(message.CommandArguments())
+
+	// Команды подписки доступны всем пользователям в личных сообщениях
+	personal := tb.config.Subscriptions && message.Chat.IsPrivate() && message.From != nil
+	if handler, exists := tb.userCommands[name]; exists && personal {
+		log.Printf("Command /%s from user %d", name, message.From.ID)
+		metrics.Inc("commands")
+
+		tb.reply(message, handler(&command{message: message, args: args, locale: tb.userLocale(message.From)}))
+		return
+	}
+
+	scope, allowed := tb.commandScope(message)
+	if !allowed {
+		if personal {
+			tb.reply(message, i18n.T(tb.userLocale(message.From), "command.unknown"))
+			return
+		}
+		tb.forbidden(message, locale)
+		return
+	}
+
+	handler, exists := tb.commands[name]
+	if !exists {
+		if message.Chat.IsPrivate() {
+			tb.reply(message, i18n.T(locale, "command.unknown"))
+		}
+		return
+	}
+
+	log.Printf("Command /%s in chat %d (scope %d)", name, message.Chat.ID, scope)
+	metrics.Inc("commands")
+
+	tb.reply(message, handler(&command{
+		message: message,
+		args:    args,
+		scope:   scope,
+		locale:  locale,
+	}))
+}
+
+// scopedDestinations возвращает чаты, которыми управляет автор команды
+func (tb *TelegramBot) scopedDestinations(scope int64) []*config.Destination {
+	if scope != 0 {
+		return tb.config.DestinationsInChat(scope)
+	}
+
+	result := make([]*config.Destination, 0, len(tb.config.Destinations))
+	for i := range tb.config.Destinations {
+		result = append(result, &tb.config.Destinations[i])
+	}
+	return result
+}
+
+func (tb *TelegramBot) cmdHelp(cmd *command) string {
+	return i18n.T(cmd.locale, "command.help")
+}
+
+func (tb *TelegramBot) cmdStatus(cmd *command) string {
+	counters := metrics.Snapshot()
+
+	lines := []string{
+		i18n.T(cmd.locale, "status.title"),
+		"",
+		i18n.T(cmd.locale, "status.uptime", time.Since(tb.startedAt).Round(time.Second)),
+		i18n.T(cmd.locale, "status.ai", aiProviderNames(tb.config.AIProviders)),
+		i18n.T(cmd.locale, "status.storage", tb.config.StorageType),
+		i18n.T(cmd.locale, "status.destinations", len(tb.config.Destinations)),
+		i18n.T(cmd.locale, "status.queue", counters["queue_length"]),
+		i18n.T(cmd.locale, "status.announcements", counters["announcements_sent"]),
+	}
+
+	if letters, err := tb.storage.ListDeadLetters(); err == nil {
+		lines = append(lines, i18n.T(cmd.locale, "status.dead_letters", len(letters)))
+	}
+
+	mutes, err := tb.storage.ListMutes()
+	if err != nil {
+		return i18n.T(cmd.locale, "command.failed", html.EscapeString(err.Error()))
+	}
+	lines = append(lines, "")
+	if len(mutes) == 0 {
+		lines = append(lines, i18n.T(cmd.locale, "status.no_mutes"))
+	} else {
+		lines = append(lines, i18n.T(cmd.locale, "status.mutes"))
+		for _, mute := range mutes {
+			lines = append(lines, "• "+tb.describeMute(mute, cmd.locale))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (tb *TelegramBot) cmdMute(cmd *command) string {
+	if len(cmd.args) == 0 || len(cmd.args) > 2 {
+		return i18n.T(cmd.locale, "mute.usage")
+	}
+
+	mute := &models.Mute{
+		ChatID:    cmd.scope,
+		Category:  strings.ToLower(cmd.args[0]),
+		CreatedAt: time.Now(),
+	}
+	if cmd.message.From != nil {
+		mute.CreatedBy = cmd.message.From.ID
+	}
+	if len(cmd.args) == 2 {
+		duration, err := time.ParseDuration(cmd.args[1])
+		if err != nil || duration <= 0 {
+			return i18n.T(cmd.locale, "mute.invalid_duration", html.EscapeString(cmd.args[1]))
+		}
+		mute.Until = mute.CreatedAt.Add(duration)
+	}
+
+	if err := tb.storage.SaveMute(mute); err != nil {
+		return i18n.T(cmd.locale, "command.failed", html.EscapeString(err.Error()))
+	}
+
+	log.Printf("Category %s muted in chat %d until %v", mute.Category, mute.ChatID, mute.Until)
+	return i18n.T(cmd.locale, "mute.done", html.EscapeString(mute.Category), tb.muteTerms(mute, cmd.locale))
+}
+
+func (tb *TelegramBot) cmdUnmute(cmd *command) string {
+	category := ""
+	if len(cmd.args) > 0 {
+		category = strings.ToLower(cmd.args[0])
+	}
+
+	removed, err := tb.storage.RemoveMutes(cmd.scope, category)
+	if err != nil {
+		return i18n.T(cmd.locale, "command.failed", html.EscapeString(err.Error()))
+	}
+	return i18n.T(cmd.locale, "unmute.done", removed)
+}
+
+func (tb *TelegramBot) cmdRoutes(cmd *command) string {
+	lines := []string{i18n.T(cmd.locale, "routes.title")}
+	for _, destination := range tb.scopedDestinations(cmd.scope) {
+		lines = append(lines, "", describeDestination(destination))
+		for _, route := range destination.Routes {
+			lines = append(lines, "• "+describeRoute(route, destination.ChatID))
+		}
+	}
+
+	var watches []string
+	for _, watch := range tb.config.Watches {
+		if cmd.scope == 0 || watch.Destination == nil || watch.Destination.ChatID == cmd.scope {
+			watches = append(watches, "• "+describeWatch(&watch))
+		}
+	}
+	if len(watches) > 0 {
+		lines = append(lines, "", i18n.T(cmd.locale, "routes.watches"))
+		lines = append(lines, watches...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (tb *TelegramBot) cmdTest(cmd *command) string {
+	var lines []string
+	for _, destination := range tb.scopedDestinations(cmd.scope) {
+		if err := tb.sendTestAnnouncement(destination); err != nil {
+			lines = append(lines, i18n.T(cmd.locale, "test.failed", html.EscapeString(destination.Name), html.EscapeString(err.Error())))
+			continue
+		}
+		lines = append(lines, i18n.T(cmd.locale, "test.sent", html.EscapeString(destination.Name)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (tb *TelegramBot) cmdResend(cmd *command) string {
+	if len(cmd.args) != 1 {
+		return i18n.T(cmd.locale, "resend.usage")
+	}
+	topicID, err := strconv.Atoi(cmd.args[0])
+	if err != nil || topicID <= 0 {
+		return i18n.T(cmd.locale, "resend.usage")
+	}
+
+	if err := tb.resendTopic(topicID, cmd.scope); err != nil {
+		log.Printf("Failed to resend topic %d: %v", topicID, err)
+		return i18n.T(cmd.locale, "command.failed", html.EscapeString(err.Error()))
+	}
+	return i18n.T(cmd.locale, "resend.done", topicID)
+}
+
+// sendTestAnnouncement отправляет в чат пример анонса по его шаблону и маршруту по умолчанию
+func (tb *TelegramBot) sendTestAnnouncement(destination *config.Destination) error {
+	locale := tb.localeFor(destination)
+	processed := &models.ProcessedWebhook{
+		Type:       "test",
+		TopicTitle: i18n.T(locale, "test.title"),
+		Author:     tb.bot.Self.UserName,
+		AuthorRole: "user",
+		Content:    i18n.T(locale, "test.content"),
+		Summary:    i18n.T(locale, "test.content"),
+		URL:        tb.config.BaseURL,
+	}
+
+	msg, err := tb.announcementMessage(destination, processed, false)
+	if err != nil {
+		return err
+	}
+	_, err = tb.send(msg)
+	return err
+}
+
+// resendTopic повторно отправляет анонс темы в чаты scope (0 - во все подходящие чаты).
+// Тема берется из сохраненного анонса, а если ее не анонсировали - загружается с форума
+func (tb *TelegramBot) resendTopic(topicID int, scope int64) error {
+	announcement, err := tb.storage.GetAnnouncement(topicID)
+	if err != nil {
+		return err
+	}
+
+	var processed *models.ProcessedWebhook
+	var isPremium bool
+	switch {
+	case announcement != nil:
+		copied := announcement.Processed
+		processed, isPremium = &copied, announcement.IsPremium
+	case tb.topicLoader != nil:
+		if processed, isPremium, err = tb.topicLoader(topicID); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("topic %d has not been announced", topicID)
+	}
+
+	var destinations []*config.Destination
+	for _, destination := range tb.config.MatchingDestinations(processed) {
+		if scope == 0 || destination.ChatID == scope {
+			destinations = append(destinations, destination)
+		}
+	}
+	if len(destinations) == 0 {
+		return fmt.Errorf("no chat accepts topic %d", topicID)
+	}
+
+	log.Printf("Resending topic %d to %d chats", topicID, len(destinations))
+	return tb.announce(processed, isPremium, destinations, make(map[string]string))
+}
+
+// withoutMuted убирает чаты, в которых категория темы отключена командой /mute
+func (tb *TelegramBot) withoutMuted(processed *models.ProcessedWebhook, destinations []*config.Destination) []*config.Destination {
+	mutes, err := tb.storage.ListMutes()
+	if err != nil {
+		log.Printf("Failed to load muted categories: %v", err)
+		return destinations
+	}
+	if len(mutes) == 0 {
+		return destinations
+	}
+
+	var result []*config.Destination
+	for _, destination := range destinations {
+		if mutedIn(mutes, destination.ChatID, processed) {
+			log.Printf("Skipping topic %d in %s - category %d is muted", processed.TopicID, destination.Name, processed.CategoryID)
+			metrics.Inc("muted_announcements")
+			continue
+		}
+		result = append(result, destination)
+	}
+	return result
+}
+
+func mutedIn(mutes []*models.Mute, chatID int64, processed *models.ProcessedWebhook) bool {
+	for _, mute := range mutes {
+		if mute.Matches(chatID, processed) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeMute описывает отключение для /status
+func (tb *TelegramBot) describeMute(mute *models.Mute, locale string) string {
+	where := i18n.T(locale, "mute.scope_all")
+	if mute.ChatID != 0 {
+		where = chatNames(tb.config.DestinationsInChat(mute.ChatID), mute.ChatID)
+	}
+	return html.EscapeString(mute.Category) + " - " + where + muteUntil(mute, locale)
+}
+
+// muteTerms описывает для ответа на /mute, где и до какого времени отключена категория
+func (tb *TelegramBot) muteTerms(mute *models.Mute, locale string) string {
+	if mute.ChatID != 0 {
+		return i18n.T(locale, "mute.scope_chat") + muteUntil(mute, locale)
+	}
+	return i18n.T(locale, "mute.scope_all") + muteUntil(mute, locale)
+}
+
+func muteUntil(mute *models.Mute, locale string) string {
+	if mute.Until.IsZero() {
+		return ""
+	}
+	return " " + i18n.T(locale, "mute.until", mute.Until.Format("02.01 15:04"))
+}
+
+// chatNames возвращает имена чатов из конфигурации или ID, если чата в ней уже нет
+func chatNames(destinations []*config.Destination, chatID int64) string {
+	if len(destinations) == 0 {
+		return strconv.FormatInt(chatID, 10)
+	}
+
+	names := make([]string, 0, len(destinations))
+	for _, destination := range destinations {
+		names = append(names, html.EscapeString(destination.Name))
+	}
+	return strings.Join(names, ", ")
+}
+
+// describeDestination описывает чат и его фильтры для /routes
+func describeDestination(destination *config.Destination) string {
+	parts := []string{fmt.Sprintf("<b>%s</b>: chat %d, %s", html.EscapeString(destination.Name), destination.ChatID, describeThread(destination.Thread))}
+	if len(destination.Categories) > 0 {
+		parts = append(parts, fmt.Sprintf("categories %v", destination.Categories))
+	}
+	if len(destination.IgnoredCategories) > 0 {
+		parts = append(parts, fmt.Sprintf("ignored_categories %v", destination.IgnoredCategories))
+	}
+	if len(destination.Tags) > 0 {
+		parts = append(parts, "tags "+html.EscapeString(strings.Join(destination.Tags, ", ")))
+	}
+	if len(destination.IgnoredTags) > 0 {
+		parts = append(parts, "ignored_tags "+html.EscapeString(strings.Join(destination.IgnoredTags, ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// describeRoute описывает правило маршрутизации: критерии и куда уходит анонс
+func describeRoute(route config.Route, chatID int64) string {
+	var criteria []string
+	if len(route.Categories) > 0 {
+		criteria = append(criteria, fmt.Sprintf("categories %v", route.Categories))
+	}
+	if len(route.CategorySlugs) > 0 {
+		criteria = append(criteria, "category_slugs "+strings.Join(route.CategorySlugs, ", "))
+	}
+	if len(route.Tags) > 0 {
+		criteria = append(criteria, "tags "+strings.Join(route.Tags, ", "))
+	}
+	if len(route.AuthorRoles) > 0 {
+		criteria = append(criteria, "author_roles "+strings.Join(route.AuthorRoles, ", "))
+	}
+
+	target := describeThread(route.Thread)
+	if route.ChatID != 0 && route.ChatID != chatID {
+		target = fmt.Sprintf("chat %d, %s", route.ChatID, target)
+	}
+	return html.EscapeString(fmt.Sprintf("%s: %s → %s", route.Name, strings.Join(criteria, "; "), target))
+}
+
+// describeWatch описывает правило наблюдения: что ищется и куда уходит совпавшая тема
+func describeWatch(watch *config.WatchRule) string {
+	var criteria []string
+	if len(watch.Keywords) > 0 {
+		criteria = append(criteria, "keywords "+strings.Join(watch.Keywords, ", "))
+	}
+	if len(watch.Patterns) > 0 {
+		patterns := make([]string, 0, len(watch.Patterns))
+		for _, pattern := range watch.Patterns {
+			patterns = append(patterns, pattern.String())
+		}
+		criteria = append(criteria, "patterns "+strings.Join(patterns, ", "))
+	}
+	if len(watch.Fields) > 0 {
+		criteria = append(criteria, "in "+strings.Join(watch.Fields, ", "))
+	}
+
+	target := "highlight only"
+	if watch.Destination != nil {
+		target = fmt.Sprintf("chat %d, %s", watch.Destination.ChatID, describeThread(watch.Destination.Thread))
+	}
+	return html.EscapeString(fmt.Sprintf("%s: %s → %s", watch.Name, strings.Join(criteria, "; "), target))
+}
+
+func describeThread(thread config.ThreadTarget) string {
+	if thread.ID == 0 {
+		return "no thread"
+	}
+	return fmt.Sprintf("thread %d (%s)", thread.ID, thread.Mode)
+}
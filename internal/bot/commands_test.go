@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/models"
+	"webhook_tg_bot/internal/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestMuteCommands(t *testing.T) {
+	store := storage.NewMemoryStorage(time.Hour, 0)
+	defer store.Close()
+	tb := &TelegramBot{config: &config.Config{}, storage: store}
+
+	main := &config.Destination{Name: "main", ChatID: -100}
+	backup := &config.Destination{Name: "backup", ChatID: -200}
+	topic := &models.ProcessedWebhook{TopicID: 1, CategoryID: 5, CategorySlug: "dev-ops"}
+
+	run := func(handler commandHandler, scope int64, args ...string) {
+		handler(&command{
+			message: &tgbotapi.Message{From: &tgbotapi.User{ID: 1}},
+			args:    args,
+			scope:   scope,
+			locale:  "en",
+		})
+	}
+	announcedTo := func() []string {
+		var names []string
+		for _, destination := range tb.withoutMuted(topic, []*config.Destination{main, backup}) {
+			names = append(names, destination.Name)
+		}
+		return names
+	}
+
+	// Администратор чата отключает категорию только у себя
+	run(tb.cmdMute, main.ChatID, "Dev-Ops", "1h")
+	if got := announcedTo(); len(got) != 1 || got[0] != "backup" {
+		t.Errorf("after /mute in main topic goes to %v, want [backup]", got)
+	}
+
+	// Неверный срок не создает отключение
+	run(tb.cmdMute, 0, "5", "soon")
+	if mutes, _ := store.ListMutes(); len(mutes) != 1 {
+		t.Errorf("/mute with invalid duration saved a mute (%d in total)", len(mutes))
+	}
+
+	// /unmute в другом чате не снимает чужое отключение
+	run(tb.cmdUnmute, backup.ChatID)
+	if got := announcedTo(); len(got) != 1 {
+		t.Errorf("/unmute in backup affected main: topic goes to %v", got)
+	}
+
+	run(tb.cmdUnmute, main.ChatID, "dev-ops")
+	if got := announcedTo(); len(got) != 2 {
+		t.Errorf("after /unmute topic goes to %v, want both chats", got)
+	}
+
+	// Глобальное отключение из личного чата действует везде
+	run(tb.cmdMute, 0, "5")
+	if got := announcedTo(); len(got) != 0 {
+		t.Errorf("after global /mute topic goes to %v, want none", got)
+	}
+}
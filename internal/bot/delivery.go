@@ -0,0 +1,200 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+	"webhook_tg_bot/internal/metrics"
+	"webhook_tg_bot/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxRetryDelay верхняя граница задержки между попытками
+const maxRetryDelay = 1 * time.Minute
+
+// ErrDeadLettered сообщение не доставлено и сохранено в dead letters. Такую ошибку
+// не нужно повторять целиком: сообщение отправляется повторно из dead letters
+var ErrDeadLettered = errors.New("message moved to dead letters")
+
+// deliver отправляет сообщение с повторами, а после последней неудачи
+// сохраняет его в хранилище недоставленных сообщений. Возвращает ID отправленного сообщения.
+// letter задает тему и сообщение, а для анонса - чат и данные, по которым анонс сохранится после повторной отправки
+func (tb *TelegramBot) deliver(letter *models.DeadLetter) (int, error) {
+	messageID, attempts, err := tb.sendWithRetry(&letter.Message)
+	if err == nil {
+		return messageID, nil
+	}
+
+	letter.ID = strconv.FormatInt(time.Now().UnixNano(), 36)
+	letter.Error = err.Error()
+	letter.Attempts = attempts
+	letter.CreatedAt = time.Now()
+	if storeErr := tb.storage.AddDeadLetter(letter); storeErr != nil {
+		log.Printf("Failed to store dead letter for topic %d: %v", letter.TopicID, storeErr)
+	} else {
+		log.Printf("Message for topic %d moved to dead letters (id: %s)", letter.TopicID, letter.ID)
+		metrics.Inc("dead_letters")
+		return 0, fmt.Errorf("%w: %w", ErrDeadLettered, err)
+	}
+
+	return 0, err
+}
+
+// sendWithRetry отправляет сообщение, повторяя попытки с экспоненциальной задержкой.
+// Возвращает ID сообщения и количество сделанных попыток
+func (tb *TelegramBot) sendWithRetry(msg *models.OutgoingMessage) (int, int, error) {
+	delay := tb.config.TelegramRetryDelay
+	attempt := 0
+
+	for {
+		attempt++
+		messageID, err := tb.send(msg)
+		if err == nil {
+			return messageID, attempt, nil
+		}
+
+		wait, retryable := retryDelay(err, delay)
+		if !retryable || attempt > tb.config.TelegramMaxRetries {
+			return 0, attempt, err
+		}
+
+		log.Printf("Telegram send failed (attempt %d): %v, retrying in %s", attempt, err, wait)
+		metrics.Inc("telegram_retries")
+		time.Sleep(wait)
+
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// send делает одну попытку отправки. Если Telegram не разобрал HTML-разметку,
+// сообщение сразу отправляется повторно простым текстом, чтобы анонс не потерялся
+func (tb *TelegramBot) send(msg *models.OutgoingMessage) (int, error) {
+	sent, err := tb.requestMessage("sendMessage", messageParams(msg))
+	if err != nil && msg.ParseMode != "" && isParseError(err) {
+		log.Printf("Telegram rejected HTML for chat %d, resending as plain text: %v", msg.ChatID, err)
+		metrics.Inc("telegram_plain_fallback")
+
+		plain := *msg
+		plain.Text = plainText(msg.Text)
+		plain.ParseMode = ""
+		sent, err = tb.requestMessage("sendMessage", messageParams(&plain))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to send telegram message: %w", err)
+	}
+
+	return sent.MessageID, nil
+}
+
+// retryDelay определяет, стоит ли повторять отправку, и сколько ждать.
+// Для 429 используется retry_after из ответа Telegram
+func retryDelay(err error, delay time.Duration) (time.Duration, bool) {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		// Сетевая ошибка - повторяем
+		return delay, true
+	}
+
+	switch {
+	case apiErr.Code == http.StatusTooManyRequests:
+		if apiErr.RetryAfter > 0 {
+			return time.Duration(apiErr.RetryAfter) * time.Second, true
+		}
+		return delay, true
+	case apiErr.Code >= http.StatusInternalServerError:
+		return delay, true
+	default:
+		// 400/403 и т.п. - повтор не поможет
+		return 0, false
+	}
+}
+
+// DeadLetters возвращает недоставленные сообщения
+func (tb *TelegramBot) DeadLetters() ([]*models.DeadLetter, error) {
+	return tb.storage.ListDeadLetters()
+}
+
+// ResendDeadLetter повторно отправляет недоставленное сообщение и удаляет его при успехе
+func (tb *TelegramBot) ResendDeadLetter(id string) error {
+	letter, err := tb.storage.GetDeadLetter(id)
+	if err != nil {
+		return err
+	}
+	if letter == nil {
+		return fmt.Errorf("dead letter %s not found", id)
+	}
+
+	// Анонс уже дошел до этого чата другим путем (например, /resend) - второй не нужен
+	if letter.Processed != nil && tb.announcedIn(letter.TopicID, letter.Destination) {
+		log.Printf("Dead letter %s is stale: topic %d is already announced in %s", id, letter.TopicID, letter.Destination)
+		return tb.storage.RemoveDeadLetter(id)
+	}
+
+	messageID, attempts, err := tb.sendWithRetry(&letter.Message)
+	if err != nil {
+		letter.Attempts += attempts
+		letter.Error = err.Error()
+		if storeErr := tb.storage.AddDeadLetter(letter); storeErr != nil {
+			log.Printf("Failed to update dead letter %s: %v", id, storeErr)
+		}
+		return err
+	}
+
+	log.Printf("Dead letter %s for topic %d resent", id, letter.TopicID)
+	if letter.Processed != nil {
+		tb.saveResentAnnouncement(letter, messageID)
+	}
+	return tb.storage.RemoveDeadLetter(id)
+}
+
+// announcedIn проверяет, есть ли у темы сохраненный анонс в чате destination
+func (tb *TelegramBot) announcedIn(topicID int, destination string) bool {
+	announcement, err := tb.storage.GetAnnouncement(topicID)
+	if err != nil || announcement == nil {
+		return false
+	}
+	return slices.ContainsFunc(announcement.Messages, func(ref models.MessageRef) bool {
+		return ref.Destination == destination
+	})
+}
+
+// saveResentAnnouncement добавляет повторно отправленный анонс к сохраненному анонсу темы,
+// чтобы правки, удаление темы и ответы доходили и до этого чата
+func (tb *TelegramBot) saveResentAnnouncement(letter *models.DeadLetter, messageID int) {
+	announcement, err := tb.storage.GetAnnouncement(letter.TopicID)
+	if err != nil {
+		log.Printf("Failed to read announcement for topic %d: %v", letter.TopicID, err)
+		return
+	}
+
+	now := time.Now()
+	if announcement == nil {
+		announcement = &models.Announcement{
+			TopicID:   letter.TopicID,
+			Processed: *letter.Processed,
+			IsPremium: letter.IsPremium,
+			CreatedAt: now,
+		}
+	}
+
+	announcement.Messages = append(announcement.Messages, models.MessageRef{
+		Destination: letter.Destination,
+		ChatID:      letter.Message.ChatID,
+		ThreadID:    letter.Message.ThreadID,
+		ThreadMode:  letter.Message.ThreadMode,
+		MessageID:   messageID,
+	})
+	announcement.UpdatedAt = now
+
+	if err := tb.storage.SaveAnnouncement(announcement); err != nil {
+		log.Printf("Failed to save announcement for topic %d: %v", letter.TopicID, err)
+	}
+}
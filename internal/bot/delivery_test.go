@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"webhook_tg_bot/internal/models"
+	"webhook_tg_bot/internal/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestRetryDelay(t *testing.T) {
+	const base = 2 * time.Second
+
+	tests := []struct {
+		name      string
+		err       error
+		wantDelay time.Duration
+		wantRetry bool
+	}{
+		{"network error", errors.New("connection reset"), base, true},
+		{"flood wait uses retry_after", &tgbotapi.Error{Code: 429, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 7}}, 7 * time.Second, true},
+		{"flood wait without retry_after", &tgbotapi.Error{Code: 429}, base, true},
+		{"telegram server error", &tgbotapi.Error{Code: 502}, base, true},
+		{"bad request", &tgbotapi.Error{Code: 400}, 0, false},
+		{"bot kicked", &tgbotapi.Error{Code: 403}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, retry := retryDelay(tt.err, base)
+			if delay != tt.wantDelay || retry != tt.wantRetry {
+				t.Errorf("retryDelay() = %s, %v, want %s, %v", delay, retry, tt.wantDelay, tt.wantRetry)
+			}
+		})
+	}
+}
+
+// deadLetterBot бот без API Telegram: попытка отправки в тесте закончится паникой
+func deadLetterBot(t *testing.T) *TelegramBot {
+	store := storage.NewMemoryStorage(time.Hour, 0)
+	t.Cleanup(func() { store.Close() })
+	return &TelegramBot{storage: store}
+}
+
+func TestResendStaleDeadLetter(t *testing.T) {
+	tb := deadLetterBot(t)
+	tb.storage.SaveAnnouncement(&models.Announcement{
+		TopicID:  42,
+		Messages: []models.MessageRef{{Destination: "main", ChatID: -100, MessageID: 1}},
+	})
+	tb.storage.AddDeadLetter(&models.DeadLetter{
+		ID:          "stale",
+		TopicID:     42,
+		Destination: "main",
+		Processed:   &models.ProcessedWebhook{TopicID: 42},
+		Message:     models.OutgoingMessage{ChatID: -100, Text: "announcement"},
+	})
+
+	if err := tb.ResendDeadLetter("stale"); err != nil {
+		t.Fatalf("ResendDeadLetter() error = %v", err)
+	}
+	if letter, _ := tb.storage.GetDeadLetter("stale"); letter != nil {
+		t.Error("stale dead letter was kept")
+	}
+}
+
+func TestResendMissingDeadLetter(t *testing.T) {
+	if err := deadLetterBot(t).ResendDeadLetter("missing"); err == nil {
+		t.Error("ResendDeadLetter() of an unknown letter returned no error")
+	}
+}
+
+func TestSaveResentAnnouncement(t *testing.T) {
+	tb := deadLetterBot(t)
+	tb.storage.SaveAnnouncement(&models.Announcement{
+		TopicID:  42,
+		Messages: []models.MessageRef{{Destination: "main", ChatID: -100, MessageID: 1}},
+	})
+
+	tb.saveResentAnnouncement(&models.DeadLetter{
+		TopicID:     42,
+		Destination: "backup",
+		Processed:   &models.ProcessedWebhook{TopicID: 42},
+		Message:     models.OutgoingMessage{ChatID: -200, ThreadID: 3},
+	}, 9)
+
+	announcement, _ := tb.storage.GetAnnouncement(42)
+	if announcement == nil || len(announcement.Messages) != 2 {
+		t.Fatalf("announcement after resend = %+v, want both chats", announcement)
+	}
+	if ref := announcement.Messages[1]; ref.Destination != "backup" || ref.ChatID != -200 || ref.ThreadID != 3 || ref.MessageID != 9 {
+		t.Errorf("resent message ref = %+v", ref)
+	}
+	if !tb.announcedIn(42, "backup") || tb.announcedIn(42, "other") {
+		t.Error("announcedIn() does not match the saved chats")
+	}
+}
@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// allowedTags теги, которые Telegram поддерживает в parse_mode=HTML
+var allowedTags = map[string]bool{
+	"b": true, "strong": true,
+	"i": true, "em": true,
+	"u": true, "ins": true,
+	"s": true, "strike": true, "del": true,
+	"code": true, "pre": true,
+	"a":          true,
+	"tg-spoiler": true,
+	"blockquote": true,
+}
+
+var (
+	tagPattern  = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9-]*)((?:\s[^<>]*)?)/?>`)
+	hrefPattern = regexp.MustCompile(`(?i)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	blankLines  = regexp.MustCompile(`\n{3,}`)
+)
+
+// sanitizeHTML оставляет в тексте (например, ответе AI) только теги, которые понимает Telegram,
+// экранирует остальное и закрывает незакрытые теги, чтобы Telegram не отклонил сообщение
+func sanitizeHTML(text string) string {
+	var result strings.Builder
+	var open []string
+
+	last := 0
+	for _, match := range tagPattern.FindAllStringSubmatchIndex(text, -1) {
+		result.WriteString(escapeText(text[last:match[0]]))
+		last = match[1]
+
+		closing := match[3] > match[2]
+		name := strings.ToLower(text[match[4]:match[5]])
+		attrs := text[match[6]:match[7]]
+
+		switch {
+		case name == "br":
+			result.WriteString("\n")
+		case name == "p" || name == "div" || name == "li":
+			if closing {
+				result.WriteString("\n")
+			}
+		case !allowedTags[name]:
+			// Неизвестный тег выбрасываем, текст внутри остается
+		case closing:
+			// Закрываем только открытый тег вместе со вложенными в него
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != name {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					result.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		case name == "a":
+			href := safeHref(attrs)
+			if href == "" {
+				continue
+			}
+			result.WriteString(`<a href="` + html.EscapeString(href) + `">`)
+			open = append(open, name)
+		default:
+			result.WriteString("<" + name + ">")
+			open = append(open, name)
+		}
+	}
+	result.WriteString(escapeText(text[last:]))
+
+	for i := len(open) - 1; i >= 0; i-- {
+		result.WriteString("</" + open[i] + ">")
+	}
+
+	return strings.TrimSpace(blankLines.ReplaceAllString(result.String(), "\n\n"))
+}
+
+// safeHref возвращает ссылку из атрибутов тега, если у нее допустимая схема
+func safeHref(attrs string) string {
+	match := hrefPattern.FindStringSubmatch(attrs)
+	if match == nil {
+		return ""
+	}
+	href := html.UnescapeString(match[1] + match[2])
+	lower := strings.ToLower(href)
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "tg://") {
+		return href
+	}
+	return ""
+}
+
+// escapeText экранирует текст для Telegram HTML. Уже записанные сущности (&nbsp;, &#39;)
+// раскрываются и экранируются заново: Telegram понимает только &lt;, &gt;, &amp;, &quot; и числовые
+func escapeText(text string) string {
+	return html.EscapeString(html.UnescapeString(text))
+}
+
+// plainText превращает сообщение в Telegram HTML в обычный текст
+func plainText(text string) string {
+	text = tagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		if strings.HasPrefix(strings.ToLower(tag), "<br") {
+			return "\n"
+		}
+		return ""
+	})
+	return html.UnescapeString(text)
+}
+
+// isParseError проверяет, что Telegram не смог разобрать разметку сообщения
+func isParseError(err error) bool {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return strings.Contains(apiErr.Message, "can't parse entities") ||
+		strings.Contains(apiErr.Message, "unsupported start tag") ||
+		strings.Contains(apiErr.Message, "can't find end tag")
+}
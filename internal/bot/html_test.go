@@ -0,0 +1,59 @@
+package bot
+
+import "testing"
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain text", "Hello, world", "Hello, world"},
+		{"allowed tags", "<b>bold</b> and <i>italic</i>", "<b>bold</b> and <i>italic</i>"},
+		{"tag names lowercased", "<B>bold</B>", "<b>bold</b>"},
+		{"unknown tag dropped", "<span>text</span>", "text"},
+		{"script tag dropped", "<script>alert(1)</script>", "alert(1)"},
+		{"unclosed tag closed", "<b>bold", "<b>bold</b>"},
+		{"nested tags closed in order", "<b><i>text</b>", "<b><i>text</i></b>"},
+		{"stray closing tag dropped", "text</b>", "text"},
+		{"br to newline", "one<br>two<br/>three", "one\ntwo\nthree"},
+		{"paragraphs to lines", "<p>one</p><p>two</p>", "one\ntwo"},
+		{"blank lines collapsed", "one\n\n\n\ntwo", "one\n\ntwo"},
+		{"special characters escaped", "a < b & c > d", "a &lt; b &amp; c &gt; d"},
+		{"entities normalized", "a&nbsp;b &#39;c&#39;", "a\u00a0b &#39;c&#39;"},
+		{"link kept", `<a href="https://example.com/t/1">topic</a>`, `<a href="https://example.com/t/1">topic</a>`},
+		{"link quotes escaped", `<a href='https://example.com/?a=1&amp;b="2"'>x</a>`, `<a href="https://example.com/?a=1&amp;b=&#34;2&#34;">x</a>`},
+		{"tg link kept", `<a href="tg://user?id=1">user</a>`, `<a href="tg://user?id=1">user</a>`},
+		{"javascript link dropped", `<a href="javascript:alert(1)">x</a>`, "x"},
+		{"link without href dropped", "<a>x</a>", "x"},
+		{"attributes of other tags dropped", `<b class="x">bold</b>`, "<b>bold</b>"},
+		{"spoiler kept", "<tg-spoiler>secret</tg-spoiler>", "<tg-spoiler>secret</tg-spoiler>"},
+		{"surrounding space trimmed", "  <b>x</b>\n", "<b>x</b>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeHTML(tt.in); got != tt.want {
+				t.Errorf("sanitizeHTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"<b>bold</b> text", "bold text"},
+		{"one<br>two", "one\ntwo"},
+		{"a &lt; b &amp; c", "a < b & c"},
+		{`<a href="https://example.com">link</a>`, "link"},
+	}
+
+	for _, tt := range tests {
+		if got := plainText(tt.in); got != tt.want {
+			t.Errorf("plainText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
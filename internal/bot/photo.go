@@ -0,0 +1,237 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/metrics"
+	"webhook_tg_bot/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// maxCaptionLength ограничение Telegram на подпись к фото (символы после разбора разметки)
+	maxCaptionLength = 1024
+	// maxPhotoSize ограничение Telegram на загружаемое фото
+	maxPhotoSize = 10 << 20
+	// maxImageRedirects сколько переадресаций допускается при скачивании картинки
+	maxImageRedirects = 5
+)
+
+// newImageClient создает клиент для скачивания картинок. Переадресация разрешена только
+// на допустимые хосты (imageAllowed), а ключ API не уходит с хоста форума: Go сохраняет
+// собственные заголовки запроса и при переходе на другой хост (S3, CDN)
+func (tb *TelegramBot) newImageClient() *http.Client {
+	return &http.Client{
+		Timeout: 15 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxImageRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxImageRedirects)
+			}
+			if !tb.imageAllowed(req.URL) {
+				return fmt.Errorf("redirect to %s is not allowed (see IMAGE_HOSTS)", req.URL.Host)
+			}
+			if !sameHost(req.URL.String(), tb.config.BaseURL) {
+				req.Header.Del("Api-Key")
+				req.Header.Del("Api-Username")
+			}
+			return nil
+		},
+	}
+}
+
+// imageAllowed проверяет, что картинку можно скачать: с хоста форума (BASE_URL) или из IMAGE_HOSTS.
+// Ссылка берется из поста, поэтому любые другие адреса, включая внутренние, не запрашиваются
+func (tb *TelegramBot) imageAllowed(imageURL *url.URL) bool {
+	if imageURL.Scheme != "http" && imageURL.Scheme != "https" {
+		return false
+	}
+	return sameHost(imageURL.String(), tb.config.BaseURL) || containsFold(tb.config.ImageHosts, imageURL.Hostname())
+}
+
+// sendPhotoAnnouncement отправляет анонс фотографией с текстом в подписи.
+// Возвращает false, если фото отправить не удалось и нужно отправить обычный текст
+func (tb *TelegramBot) sendPhotoAnnouncement(destination *config.Destination, processed *models.ProcessedWebhook, isPremium bool, msg *models.OutgoingMessage) (*models.MessageRef, bool) {
+	caption, ok := tb.fitCaption(destination, processed, isPremium)
+	if !ok {
+		log.Printf("Announcement for topic %d does not fit into a photo caption, sending text", processed.TopicID)
+		return nil, false
+	}
+
+	image, err := tb.downloadImage(processed.ImageURL)
+	if err != nil {
+		log.Printf("Failed to download image for topic %d, sending text: %v", processed.TopicID, err)
+		metrics.Inc("photo_download_failures")
+		return nil, false
+	}
+
+	photo := *msg
+	photo.Text = caption
+	messageID, err := tb.sendPhoto(&photo, image)
+	if err != nil {
+		log.Printf("Failed to send photo for topic %d, sending text: %v", processed.TopicID, err)
+		metrics.Inc("photo_send_failures")
+		return nil, false
+	}
+
+	metrics.Inc("photo_announcements")
+	return &models.MessageRef{
+		Destination: destination.Name,
+		ChatID:      photo.ChatID,
+		ThreadID:    photo.ThreadID,
+		ThreadMode:  photo.ThreadMode,
+		MessageID:   messageID,
+		Kind:        models.MessageKindPhoto,
+	}, true
+}
+
+// fitCaption формирует подпись к фото не длиннее maxCaptionLength.
+// Если анонс не помещается, сокращается резюме; false - не помещается и без него
+func (tb *TelegramBot) fitCaption(destination *config.Destination, processed *models.ProcessedWebhook, isPremium bool) (string, bool) {
+	caption, err := tb.formatAnnouncement(destination, processed, isPremium)
+	if err != nil {
+		return "", false
+	}
+
+	overflow := captionLength(caption) - maxCaptionLength
+	if overflow <= 0 {
+		return caption, true
+	}
+
+	summaryLength := utf8.RuneCountInString(processed.Summary)
+	if summaryLength == 0 {
+		return "", false
+	}
+
+	// Обрезаем резюме с запасом на многоточие
+	keep := summaryLength - overflow - 1
+	shortened := *processed
+	shortened.Summary = ""
+	if keep > 0 {
+		shortened.Summary = strings.TrimSpace(string([]rune(processed.Summary)[:keep])) + "…"
+	}
+
+	caption, err = tb.formatAnnouncement(destination, &shortened, isPremium)
+	if err != nil || captionLength(caption) > maxCaptionLength {
+		return "", false
+	}
+	return caption, true
+}
+
+// captionLength считает длину подписи так же, как Telegram: без разметки, в UTF-16
+func captionLength(text string) int {
+	return len(utf16.Encode([]rune(strings.TrimSpace(plainText(text)))))
+}
+
+// downloadImage скачивает картинку. Картинки самого форума запрашиваются с ключом API,
+// чтобы работали и закрытые разделы
+func (tb *TelegramBot) downloadImage(imageURL string) (tgbotapi.FileBytes, error) {
+	req, err := http.NewRequest(http.MethodGet, imageURL, nil)
+	if err != nil {
+		return tgbotapi.FileBytes{}, err
+	}
+	if !tb.imageAllowed(req.URL) {
+		return tgbotapi.FileBytes{}, fmt.Errorf("host %s is not allowed (see IMAGE_HOSTS)", req.URL.Host)
+	}
+	if tb.config.DiscourseAPIKey != "" && sameHost(imageURL, tb.config.BaseURL) {
+		req.Header.Set("Api-Key", tb.config.DiscourseAPIKey)
+		req.Header.Set("Api-Username", tb.config.DiscourseAPIUsername)
+	}
+
+	resp, err := tb.imageClient.Do(req)
+	if err != nil {
+		return tgbotapi.FileBytes{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return tgbotapi.FileBytes{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "image/") {
+		return tgbotapi.FileBytes{}, fmt.Errorf("unexpected content type %q", contentType)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPhotoSize+1))
+	if err != nil {
+		return tgbotapi.FileBytes{}, err
+	}
+	if len(data) > maxPhotoSize {
+		return tgbotapi.FileBytes{}, fmt.Errorf("image is larger than %d bytes", maxPhotoSize)
+	}
+
+	name := "image"
+	if parsed, err := url.Parse(imageURL); err == nil && path.Base(parsed.Path) != "/" {
+		name = path.Base(parsed.Path)
+	}
+	return tgbotapi.FileBytes{Name: name, Bytes: data}, nil
+}
+
+// sendPhoto отправляет фото с подписью. Если Telegram не разобрал разметку подписи,
+// фото отправляется повторно с подписью простым текстом
+func (tb *TelegramBot) sendPhoto(msg *models.OutgoingMessage, image tgbotapi.FileBytes) (int, error) {
+	messageID, err := tb.uploadPhoto(msg, image)
+	if err != nil && msg.ParseMode != "" && isParseError(err) {
+		log.Printf("Telegram rejected HTML caption for chat %d, resending as plain text: %v", msg.ChatID, err)
+		metrics.Inc("telegram_plain_fallback")
+
+		plain := *msg
+		plain.Text = plainText(msg.Text)
+		plain.ParseMode = ""
+		messageID, err = tb.uploadPhoto(&plain, image)
+	}
+	return messageID, err
+}
+
+func (tb *TelegramBot) uploadPhoto(msg *models.OutgoingMessage, image tgbotapi.FileBytes) (int, error) {
+	params := make(tgbotapi.Params)
+	params.AddFirstValid("chat_id", msg.ChatID)
+	params.AddNonEmpty("caption", msg.Text)
+	params.AddNonEmpty("parse_mode", msg.ParseMode)
+	addThreadParams(params, msg)
+	addKeyboardParams(params, msg)
+
+	resp, err := tb.bot.UploadFiles("sendPhoto", params, []tgbotapi.RequestFile{{Name: "photo", Data: image}})
+	if err != nil {
+		return 0, err
+	}
+
+	var message tgbotapi.Message
+	if err := json.Unmarshal(resp.Result, &message); err != nil {
+		return 0, fmt.Errorf("failed to decode sendPhoto response: %v", err)
+	}
+	return message.MessageID, nil
+}
+
+// editCaption заменяет подпись к фото, при ошибке разметки - простым текстом
+func (tb *TelegramBot) editCaption(chatID int64, messageID int, caption, parseMode string, keyboard [][]models.InlineButton) error {
+	edit := tgbotapi.NewEditMessageCaption(chatID, messageID, caption)
+	edit.ParseMode = parseMode
+	edit.ReplyMarkup = inlineKeyboard(keyboard)
+
+	_, err := tb.bot.Request(edit)
+	if err != nil && parseMode != "" && isParseError(err) {
+		log.Printf("Telegram rejected HTML caption in message %d, editing as plain text: %v", messageID, err)
+		metrics.Inc("telegram_plain_fallback")
+		edit.Caption = plainText(caption)
+		edit.ParseMode = ""
+		_, err = tb.bot.Request(edit)
+	}
+	return err
+}
+
+// sameHost проверяет, что ссылка ведет на хост форума
+func sameHost(link, baseURL string) bool {
+	a, errA := url.Parse(link)
+	b, errB := url.Parse(baseURL)
+	return errA == nil && errB == nil && a.Host != "" && strings.EqualFold(a.Host, b.Host)
+}
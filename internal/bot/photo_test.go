@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"webhook_tg_bot/internal/config"
+)
+
+// imageServer отдает картинку и запоминает, пришел ли запрос с ключом API
+func imageServer(t *testing.T, gotKey *string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*gotKey = r.Header.Get("Api-Key")
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// redirectServer изображает форум, который отправляет загрузку на target
+func redirectServer(t *testing.T, target string) *httptest.Server {
+	server := httptest.NewServer(http.RedirectHandler(target, http.StatusFound))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func photoBot(baseURL string, imageHosts ...string) *TelegramBot {
+	tb := &TelegramBot{config: &config.Config{
+		BaseURL:              baseURL,
+		DiscourseAPIKey:      "secret",
+		DiscourseAPIUsername: "system",
+		ImageHosts:           imageHosts,
+	}}
+	tb.imageClient = tb.newImageClient()
+	return tb
+}
+
+func TestDownloadImageFromForumSendsKey(t *testing.T) {
+	var gotKey string
+	forum := imageServer(t, &gotKey)
+
+	image, err := photoBot(forum.URL).downloadImage(forum.URL + "/uploads/a.png")
+	if err != nil {
+		t.Fatalf("downloadImage() error = %v", err)
+	}
+	if gotKey != "secret" {
+		t.Errorf("forum request Api-Key = %q, want secret", gotKey)
+	}
+	if image.Name != "a.png" || string(image.Bytes) != "png" {
+		t.Errorf("downloadImage() = %s %q, want a.png \"png\"", image.Name, image.Bytes)
+	}
+}
+
+func TestDownloadImageRejectsOtherHosts(t *testing.T) {
+	var gotKey string
+	other := imageServer(t, &gotKey)
+
+	_, err := photoBot("https://forum.example.com").downloadImage(other.URL + "/a.png")
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("downloadImage() error = %v, want host not allowed", err)
+	}
+}
+
+func TestDownloadImageRedirect(t *testing.T) {
+	var gotKey string
+	cdn := imageServer(t, &gotKey)
+	forum := redirectServer(t, cdn.URL+"/a.png")
+
+	// Без IMAGE_HOSTS переадресация с форума на чужой хост запрещена
+	if _, err := photoBot(forum.URL).downloadImage(forum.URL + "/uploads/a.png"); err == nil {
+		t.Error("downloadImage() followed a redirect to a host outside IMAGE_HOSTS")
+	}
+
+	// Разрешенный CDN получает запрос, но без ключа API форума
+	gotKey = "unset"
+	if _, err := photoBot(forum.URL, "127.0.0.1").downloadImage(forum.URL + "/uploads/a.png"); err != nil {
+		t.Fatalf("downloadImage() error = %v", err)
+	}
+	if gotKey != "" {
+		t.Errorf("CDN request Api-Key = %q, want it removed", gotKey)
+	}
+}
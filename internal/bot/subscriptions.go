@@ -0,0 +1,556 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/i18n"
+	"webhook_tg_bot/internal/metrics"
+	"webhook_tg_bot/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// subscriptionQueueSize сколько тем может ждать рассылки подписчикам
+	subscriptionQueueSize = 100
+	// maxSubscriptionValues ограничение на количество критериев одного вида
+	maxSubscriptionValues = 50
+	// categoriesPageSize категорий на странице меню
+	categoriesPageSize = 10
+	// categoriesCacheTTL как долго используется загруженный с форума список категорий
+	categoriesCacheTTL = 10 * time.Minute
+)
+
+// subscriptionKinds виды критериев в порядке вывода в меню
+var subscriptionKinds = []string{models.SubscriptionCategory, models.SubscriptionTag, models.SubscriptionAuthor, models.SubscriptionKeyword}
+
+// subscriptionJob тема, которую нужно разослать подписчикам
+type subscriptionJob struct {
+	processed *models.ProcessedWebhook
+	isPremium bool
+	summaries map[string]string // резюме, уже полученные для чатов
+}
+
+// startNotifier запускает рассылку тем подписчикам с ограничением SUBSCRIPTION_RATE
+func (tb *TelegramBot) startNotifier() {
+	tb.notifications = make(chan *subscriptionJob, subscriptionQueueSize)
+
+	go func() {
+		limiter := time.NewTicker(time.Second / time.Duration(tb.config.SubscriptionRate))
+		defer limiter.Stop()
+
+		for job := range tb.notifications {
+			tb.deliverToSubscribers(job, limiter.C)
+		}
+	}()
+}
+
+// notifySubscribers ставит тему в очередь рассылки подписчикам, не задерживая анонс в чатах
+func (tb *TelegramBot) notifySubscribers(processed *models.ProcessedWebhook, isPremium bool, summaries map[string]string) {
+	if tb.notifications == nil {
+		return
+	}
+
+	copied := *processed
+	select {
+	case tb.notifications <- &subscriptionJob{processed: &copied, isPremium: isPremium, summaries: summaries}:
+	default:
+		log.Printf("Subscription queue is full, topic %d is not sent to subscribers", processed.TopicID)
+		metrics.Inc("subscription_dropped")
+	}
+}
+
+// deliverToSubscribers отправляет тему каждому подходящему подписчику, не чаще одного сообщения за тик
+func (tb *TelegramBot) deliverToSubscribers(job *subscriptionJob, tick <-chan time.Time) {
+	// Темы закрытых категорий не рассылаются, даже если совпал тег, автор или ключевое слово
+	if !tb.publicCategory(job.processed.CategoryID) {
+		log.Printf("Topic %d is not sent to subscribers - category %d is not public", job.processed.TopicID, job.processed.CategoryID)
+		return
+	}
+
+	subscriptions, err := tb.storage.ListSubscriptions()
+	if err != nil {
+		log.Printf("Failed to load subscriptions: %v", err)
+		return
+	}
+
+	delivered := 0
+	for _, subscription := range subscriptions {
+		if !subscription.Matches(job.processed) {
+			continue
+		}
+		<-tick
+
+		destination := tb.subscriberDestination(subscription)
+		msg, err := tb.announcementMessage(destination, tb.withSummary(job.processed, destination, job.summaries), job.isPremium)
+		if err == nil {
+			_, err = tb.send(msg)
+		}
+		if err != nil {
+			metrics.Inc("subscription_failures")
+			// Пользователь заблокировал бота или удалил аккаунт - подписка больше не нужна
+			var apiErr *tgbotapi.Error
+			if errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden {
+				log.Printf("Removing subscription of user %d: %v", subscription.UserID, err)
+				if err := tb.storage.RemoveSubscription(subscription.UserID); err != nil {
+					log.Printf("Failed to remove subscription of user %d: %v", subscription.UserID, err)
+				}
+				continue
+			}
+			log.Printf("Failed to send topic %d to subscriber %d: %v", job.processed.TopicID, subscription.UserID, err)
+			continue
+		}
+		delivered++
+	}
+
+	if delivered > 0 {
+		log.Printf("Topic %d sent to %d subscribers", job.processed.TopicID, delivered)
+		metrics.Add("subscription_messages", int64(delivered))
+	}
+}
+
+// subscriberDestination описывает личный чат подписчика как чат с шаблонами по умолчанию
+func (tb *TelegramBot) subscriberDestination(subscription *models.Subscription) *config.Destination {
+	locale := subscription.Locale
+	if locale == "" {
+		locale = tb.config.DefaultLocale
+	}
+	return &config.Destination{
+		Name:          fmt.Sprintf("dm:%d", subscription.UserID),
+		ChatID:        subscription.UserID,
+		PremiumNotice: true,
+		Locale:        locale,
+		Buttons:       []config.Button{{Type: config.ButtonTopic}},
+	}
+}
+
+// setupUserCommands регистрирует команды личных подписок, доступные всем в личных сообщениях
+func (tb *TelegramBot) setupUserCommands() {
+	tb.userCommands = map[string]commandHandler{
+		"start":         tb.cmdStart,
+		"help":          tb.cmdUserHelp,
+		"subscribe":     tb.cmdSubscribe,
+		"unsubscribe":   tb.cmdUnsubscribe,
+		"subscriptions": tb.cmdSubscriptions,
+	}
+}
+
+// userLocale возвращает язык пользователя: из подписки, из настроек Telegram или DEFAULT_LOCALE
+func (tb *TelegramBot) userLocale(user *tgbotapi.User) string {
+	if user == nil {
+		return tb.config.DefaultLocale
+	}
+	if subscription, err := tb.storage.GetSubscription(user.ID); err == nil && subscription != nil && subscription.Locale != "" {
+		return subscription.Locale
+	}
+	if locale := i18n.Normalize(user.LanguageCode); i18n.Supported(locale) {
+		return locale
+	}
+	return tb.config.DefaultLocale
+}
+
+// loadSubscription возвращает подписку пользователя или новую пустую подписку
+func (tb *TelegramBot) loadSubscription(userID int64, locale string) (*models.Subscription, error) {
+	subscription, err := tb.storage.GetSubscription(userID)
+	if err != nil || subscription != nil {
+		return subscription, err
+	}
+	now := time.Now()
+	return &models.Subscription{UserID: userID, Locale: locale, CreatedAt: now, UpdatedAt: now}, nil
+}
+
+func (tb *TelegramBot) saveSubscription(subscription *models.Subscription) error {
+	subscription.UpdatedAt = time.Now()
+	return tb.storage.SaveSubscription(subscription)
+}
+
+func (tb *TelegramBot) cmdStart(cmd *command) string {
+	subscription, err := tb.loadSubscription(cmd.message.From.ID, cmd.locale)
+	if err == nil {
+		err = tb.saveSubscription(subscription)
+	}
+	if err != nil {
+		return i18n.T(cmd.locale, "command.failed", html.EscapeString(err.Error()))
+	}
+
+	text, keyboard := tb.subscriptionMenu(subscription, cmd.locale)
+	tb.sendMenu(cmd.message.Chat.ID, i18n.T(cmd.locale, "subscription.welcome")+"\n\n"+text, keyboard)
+	return ""
+}
+
+func (tb *TelegramBot) cmdUserHelp(cmd *command) string {
+	text := i18n.T(cmd.locale, "subscription.help")
+	if tb.config.IsAdminUser(cmd.message.From.ID) {
+		text += "\n\n" + i18n.T(cmd.locale, "command.help")
+	}
+	return text
+}
+
+func (tb *TelegramBot) cmdSubscriptions(cmd *command) string {
+	subscription, err := tb.loadSubscription(cmd.message.From.ID, cmd.locale)
+	if err != nil {
+		return i18n.T(cmd.locale, "command.failed", html.EscapeString(err.Error()))
+	}
+
+	text, keyboard := tb.subscriptionMenu(subscription, cmd.locale)
+	tb.sendMenu(cmd.message.Chat.ID, text, keyboard)
+	return ""
+}
+
+func (tb *TelegramBot) cmdSubscribe(cmd *command) string {
+	kind, value, ok := subscriptionArgs(cmd.args)
+	if !ok {
+		return i18n.T(cmd.locale, "subscription.usage")
+	}
+
+	subscription, err := tb.loadSubscription(cmd.message.From.ID, cmd.locale)
+	if err != nil {
+		return i18n.T(cmd.locale, "command.failed", html.EscapeString(err.Error()))
+	}
+
+	values := subscription.Values(kind)
+	if containsFold(*values, value) {
+		return i18n.T(cmd.locale, "subscription.exists", html.EscapeString(value))
+	}
+	if len(*values) >= maxSubscriptionValues {
+		return i18n.T(cmd.locale, "subscription.limit", maxSubscriptionValues)
+	}
+	*values = append(*values, value)
+
+	if err := tb.saveSubscription(subscription); err != nil {
+		return i18n.T(cmd.locale, "command.failed", html.EscapeString(err.Error()))
+	}
+	return i18n.T(cmd.locale, "subscription.added", i18n.T(cmd.locale, "subscription.kind."+kind), html.EscapeString(value))
+}
+
+func (tb *TelegramBot) cmdUnsubscribe(cmd *command) string {
+	// Без аргументов пользователь отписывается от всего
+	if len(cmd.args) == 0 {
+		if err := tb.storage.RemoveSubscription(cmd.message.From.ID); err != nil {
+			return i18n.T(cmd.locale, "command.failed", html.EscapeString(err.Error()))
+		}
+		return i18n.T(cmd.locale, "subscription.stopped")
+	}
+
+	kind, value, ok := subscriptionArgs(cmd.args)
+	if !ok {
+		return i18n.T(cmd.locale, "subscription.usage")
+	}
+
+	subscription, err := tb.storage.GetSubscription(cmd.message.From.ID)
+	if err != nil {
+		return i18n.T(cmd.locale, "command.failed", html.EscapeString(err.Error()))
+	}
+	if subscription == nil || !removeFold(subscription.Values(kind), value) {
+		return i18n.T(cmd.locale, "subscription.not_found", html.EscapeString(value))
+	}
+
+	if err := tb.saveSubscription(subscription); err != nil {
+		return i18n.T(cmd.locale, "command.failed", html.EscapeString(err.Error()))
+	}
+	return i18n.T(cmd.locale, "subscription.removed", html.EscapeString(value))
+}
+
+// subscriptionArgs разбирает "<вид> <значение>" в командах /subscribe и /unsubscribe
+func subscriptionArgs(args []string) (string, string, bool) {
+	if len(args) < 2 {
+		return "", "", false
+	}
+
+	kind := strings.ToLower(args[0])
+	value := strings.Join(args[1:], " ")
+	switch kind {
+	case models.SubscriptionCategory, models.SubscriptionTag:
+		value = strings.ToLower(value)
+	case models.SubscriptionAuthor:
+		value = strings.TrimPrefix(value, "@")
+	case models.SubscriptionKeyword:
+	default:
+		return "", "", false
+	}
+	return kind, value, value != ""
+}
+
+// sendMenu отправляет меню подписки в личный чат
+func (tb *TelegramBot) sendMenu(chatID int64, text string, keyboard [][]models.InlineButton) {
+	msg := &models.OutgoingMessage{ChatID: chatID, Text: text, ParseMode: "HTML", Keyboard: keyboard}
+	if _, err := tb.send(msg); err != nil {
+		log.Printf("Failed to send subscription menu to %d: %v", chatID, err)
+	}
+}
+
+// subscriptionMenu главное меню: текущие критерии и кнопки для их изменения
+func (tb *TelegramBot) subscriptionMenu(subscription *models.Subscription, locale string) (string, [][]models.InlineButton) {
+	lines := []string{i18n.T(locale, "subscription.title")}
+	for _, kind := range subscriptionKinds {
+		values := *subscription.Values(kind)
+		if kind == models.SubscriptionCategory {
+			values = tb.categoryNames(values)
+		}
+
+		list := i18n.T(locale, "subscription.none")
+		if len(values) > 0 {
+			list = html.EscapeString(strings.Join(values, ", "))
+		}
+		lines = append(lines, i18n.T(locale, "subscription.kind."+kind)+": "+list)
+	}
+	lines = append(lines, "", i18n.T(locale, "subscription.hint"))
+
+	keyboard := [][]models.InlineButton{
+		{
+			{Text: i18n.T(locale, "subscription.kind.category"), CallbackData: "sub:cats:0"},
+			{Text: i18n.T(locale, "subscription.kind.tag"), CallbackData: "sub:list:tag"},
+		},
+		{
+			{Text: i18n.T(locale, "subscription.kind.author"), CallbackData: "sub:list:author"},
+			{Text: i18n.T(locale, "subscription.kind.keyword"), CallbackData: "sub:list:keyword"},
+		},
+	}
+	if !subscription.Empty() {
+		keyboard = append(keyboard, []models.InlineButton{{Text: i18n.T(locale, "subscription.stop"), CallbackData: "sub:stop"}})
+	}
+	return strings.Join(lines, "\n"), keyboard
+}
+
+// categoriesMenu страница категорий форума; нажатие подписывает на категорию или отписывает от нее
+func (tb *TelegramBot) categoriesMenu(subscription *models.Subscription, locale string, page int) (string, [][]models.InlineButton) {
+	categories, err := tb.forumCategories()
+	if err != nil || len(categories) == 0 {
+		if err != nil {
+			log.Printf("Failed to load forum categories: %v", err)
+		}
+		// Без списка с форума остаются подписки, добавленные командой
+		text, keyboard := valuesMenu(subscription, models.SubscriptionCategory, locale)
+		return i18n.T(locale, "subscription.categories_unavailable") + "\n\n" + text, keyboard
+	}
+
+	pages := (len(categories) + categoriesPageSize - 1) / categoriesPageSize
+	page = max(0, min(page, pages-1))
+
+	var keyboard [][]models.InlineButton
+	for _, category := range categories[page*categoriesPageSize : min(len(categories), (page+1)*categoriesPageSize)] {
+		text := category.Name
+		if subscribedToCategory(subscription, category) {
+			text = "✅ " + text
+		}
+		keyboard = append(keyboard, []models.InlineButton{{Text: text, CallbackData: fmt.Sprintf("sub:cat:%d:%d", category.ID, page)}})
+	}
+
+	var navigation []models.InlineButton
+	if page > 0 {
+		navigation = append(navigation, models.InlineButton{Text: "«", CallbackData: fmt.Sprintf("sub:cats:%d", page-1)})
+	}
+	navigation = append(navigation, models.InlineButton{Text: i18n.T(locale, "subscription.back"), CallbackData: "sub:menu"})
+	if page < pages-1 {
+		navigation = append(navigation, models.InlineButton{Text: "»", CallbackData: fmt.Sprintf("sub:cats:%d", page+1)})
+	}
+	keyboard = append(keyboard, navigation)
+
+	return i18n.T(locale, "subscription.categories", page+1, pages), keyboard
+}
+
+// valuesMenu список критериев одного вида; нажатие удаляет критерий
+func valuesMenu(subscription *models.Subscription, kind, locale string) (string, [][]models.InlineButton) {
+	var keyboard [][]models.InlineButton
+	for i, value := range *subscription.Values(kind) {
+		keyboard = append(keyboard, []models.InlineButton{{Text: "❌ " + value, CallbackData: fmt.Sprintf("sub:rm:%s:%d", kind, i)}})
+	}
+	keyboard = append(keyboard, []models.InlineButton{{Text: i18n.T(locale, "subscription.back"), CallbackData: "sub:menu"}})
+
+	return i18n.T(locale, "subscription.list", i18n.T(locale, "subscription.kind."+kind), kind), keyboard
+}
+
+// handleCallback обрабатывает нажатия кнопок меню подписки в личном чате
+func (tb *TelegramBot) handleCallback(callback *tgbotapi.CallbackQuery) {
+	// Ответ убирает индикатор загрузки на кнопке
+	defer func() {
+		if _, err := tb.bot.Request(tgbotapi.NewCallback(callback.ID, "")); err != nil {
+			log.Printf("Failed to answer callback query: %v", err)
+		}
+	}()
+
+	if !tb.config.Subscriptions || callback.Message == nil || !callback.Message.Chat.IsPrivate() {
+		return
+	}
+	action, ok := strings.CutPrefix(callback.Data, "sub:")
+	if !ok {
+		return
+	}
+
+	locale := tb.userLocale(callback.From)
+	subscription, err := tb.loadSubscription(callback.From.ID, locale)
+	if err != nil {
+		log.Printf("Failed to load subscription of user %d: %v", callback.From.ID, err)
+		return
+	}
+
+	parts := strings.Split(action, ":")
+	var text string
+	var keyboard [][]models.InlineButton
+	switch {
+	case parts[0] == "cats" && len(parts) == 2:
+		page, _ := strconv.Atoi(parts[1])
+		text, keyboard = tb.categoriesMenu(subscription, locale, page)
+
+	case parts[0] == "cat" && len(parts) == 3:
+		categoryID, _ := strconv.Atoi(parts[1])
+		page, _ := strconv.Atoi(parts[2])
+		if err := tb.toggleCategory(subscription, categoryID); err != nil {
+			log.Printf("Failed to update subscription of user %d: %v", callback.From.ID, err)
+			return
+		}
+		text, keyboard = tb.categoriesMenu(subscription, locale, page)
+
+	case parts[0] == "list" && len(parts) == 2 && subscription.Values(parts[1]) != nil:
+		text, keyboard = valuesMenu(subscription, parts[1], locale)
+
+	case parts[0] == "rm" && len(parts) == 3 && subscription.Values(parts[1]) != nil:
+		values := subscription.Values(parts[1])
+		if i, err := strconv.Atoi(parts[2]); err == nil && i >= 0 && i < len(*values) {
+			*values = slices.Delete(*values, i, i+1)
+			if err := tb.saveSubscription(subscription); err != nil {
+				log.Printf("Failed to update subscription of user %d: %v", callback.From.ID, err)
+				return
+			}
+		}
+		text, keyboard = valuesMenu(subscription, parts[1], locale)
+
+	case parts[0] == "stop":
+		if err := tb.storage.RemoveSubscription(callback.From.ID); err != nil {
+			log.Printf("Failed to remove subscription of user %d: %v", callback.From.ID, err)
+			return
+		}
+		subscription, _ = tb.loadSubscription(callback.From.ID, locale)
+		text, keyboard = tb.subscriptionMenu(subscription, locale)
+		text = i18n.T(locale, "subscription.stopped") + "\n\n" + text
+
+	default:
+		text, keyboard = tb.subscriptionMenu(subscription, locale)
+	}
+
+	err = tb.editHTML(callback.Message.Chat.ID, callback.Message.MessageID, text, keyboard)
+	if err != nil && !isNotModified(err) {
+		log.Printf("Failed to update subscription menu for user %d: %v", callback.From.ID, err)
+	}
+}
+
+// toggleCategory подписывает на категорию или отписывает от нее
+func (tb *TelegramBot) toggleCategory(subscription *models.Subscription, categoryID int) error {
+	categories, _ := tb.forumCategories()
+	category := models.Category{ID: categoryID}
+	listed := false
+	for _, c := range categories {
+		if c.ID == categoryID {
+			category, listed = c, true
+		}
+	}
+
+	if subscribedToCategory(subscription, category) {
+		subscription.Categories = slices.DeleteFunc(subscription.Categories, func(value string) bool {
+			return value == strconv.Itoa(category.ID) || (category.Slug != "" && strings.EqualFold(value, category.Slug))
+		})
+	} else {
+		// Подписаться кнопкой можно только на категорию из меню
+		if !listed || len(subscription.Categories) >= maxSubscriptionValues {
+			return nil
+		}
+		subscription.Categories = append(subscription.Categories, strconv.Itoa(category.ID))
+	}
+	return tb.saveSubscription(subscription)
+}
+
+func subscribedToCategory(subscription *models.Subscription, category models.Category) bool {
+	for _, value := range subscription.Categories {
+		if value == strconv.Itoa(category.ID) || (category.Slug != "" && strings.EqualFold(value, category.Slug)) {
+			return true
+		}
+	}
+	return false
+}
+
+// publicCategory проверяет, что категория есть в списке для подписки; при ошибке загрузки - нет
+func (tb *TelegramBot) publicCategory(categoryID int) bool {
+	categories, err := tb.forumCategories()
+	if err != nil {
+		log.Printf("Failed to load forum categories: %v", err)
+		return false
+	}
+	for _, category := range categories {
+		if category.ID == categoryID {
+			return true
+		}
+	}
+	return false
+}
+
+// forumCategories возвращает публичные категории форума, которые анонсируются хотя бы в один чат.
+// Список кешируется на categoriesCacheTTL
+func (tb *TelegramBot) forumCategories() ([]models.Category, error) {
+	tb.categoriesMutex.Lock()
+	defer tb.categoriesMutex.Unlock()
+
+	if tb.categories != nil && time.Since(tb.categoriesLoadedAt) < categoriesCacheTTL {
+		return tb.categories, nil
+	}
+
+	categories, err := tb.discourse.GetCategories()
+	if err != nil {
+		// Устаревший список лучше, чем никакого
+		if tb.categories != nil {
+			return tb.categories, nil
+		}
+		return nil, err
+	}
+
+	tb.categories = slices.DeleteFunc(categories, func(category models.Category) bool {
+		return !tb.config.AcceptsCategory(category.ID)
+	})
+	tb.categoriesLoadedAt = time.Now()
+	return tb.categories, nil
+}
+
+// categoryNames заменяет ID категорий названиями из последнего загруженного списка
+func (tb *TelegramBot) categoryNames(values []string) []string {
+	tb.categoriesMutex.Lock()
+	categories := tb.categories
+	tb.categoriesMutex.Unlock()
+
+	names := make([]string, 0, len(values))
+	for _, value := range values {
+		name := value
+		for _, category := range categories {
+			if value == strconv.Itoa(category.ID) || strings.EqualFold(value, category.Slug) {
+				name = category.Name
+				break
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFold удаляет значение без учета регистра; false - значения не было
+func removeFold(values *[]string, value string) bool {
+	before := len(*values)
+	*values = slices.DeleteFunc(*values, func(v string) bool {
+		return strings.EqualFold(v, value)
+	})
+	return len(*values) != before
+}
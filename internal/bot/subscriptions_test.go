@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"slices"
+	"testing"
+
+	"webhook_tg_bot/internal/models"
+)
+
+func TestSubscriptionArgs(t *testing.T) {
+	tests := []struct {
+		args      []string
+		wantKind  string
+		wantValue string
+		wantOK    bool
+	}{
+		{[]string{"category", "Dev-Ops"}, models.SubscriptionCategory, "dev-ops", true},
+		{[]string{"TAG", "Kubernetes"}, models.SubscriptionTag, "kubernetes", true},
+		{[]string{"author", "@Alice"}, models.SubscriptionAuthor, "Alice", true},
+		{[]string{"keyword", "Out", "of", "memory"}, models.SubscriptionKeyword, "Out of memory", true},
+		{[]string{"author", "@"}, models.SubscriptionAuthor, "", false},
+		{[]string{"color", "red"}, "", "", false},
+		{[]string{"tag"}, "", "", false},
+	}
+
+	for _, tt := range tests {
+		kind, value, ok := subscriptionArgs(tt.args)
+		if kind != tt.wantKind || value != tt.wantValue || ok != tt.wantOK {
+			t.Errorf("subscriptionArgs(%q) = %q, %q, %v, want %q, %q, %v",
+				tt.args, kind, value, ok, tt.wantKind, tt.wantValue, tt.wantOK)
+		}
+	}
+}
+
+func TestSubscriptionMatches(t *testing.T) {
+	topic := &models.ProcessedWebhook{
+		TopicTitle:   "Pods keep restarting",
+		Content:      "OOMKilled after upgrade",
+		CategoryID:   5,
+		CategorySlug: "dev-ops",
+		Tags:         []string{"kubernetes"},
+		Author:       "Alice",
+	}
+
+	matching := []models.Subscription{
+		{Categories: []string{"5"}},
+		{Categories: []string{"DEV-OPS"}},
+		{Tags: []string{"Kubernetes"}},
+		{Authors: []string{"alice"}},
+		{Keywords: []string{"oomkilled"}},
+		{Tags: []string{"docker"}, Authors: []string{"alice"}},
+	}
+	for _, subscription := range matching {
+		if !subscription.Matches(topic) {
+			t.Errorf("%+v does not match the topic", subscription)
+		}
+	}
+
+	other := []models.Subscription{
+		{},
+		{Categories: []string{"6", "general"}},
+		{Tags: []string{"docker"}},
+		{Authors: []string{"bob"}},
+		{Keywords: []string{"postgres"}},
+	}
+	for _, subscription := range other {
+		if subscription.Matches(topic) {
+			t.Errorf("%+v matches an unrelated topic", subscription)
+		}
+	}
+}
+
+func TestToggleSubscriptionValues(t *testing.T) {
+	subscription := &models.Subscription{Tags: []string{"Kubernetes", "docker"}}
+	tags := subscription.Values(models.SubscriptionTag)
+
+	if !containsFold(*tags, "kubernetes") {
+		t.Error("containsFold() ignores case mismatch")
+	}
+	if !removeFold(tags, "KUBERNETES") || !slices.Equal(subscription.Tags, []string{"docker"}) {
+		t.Errorf("removeFold() left %v, want [docker]", subscription.Tags)
+	}
+	if removeFold(tags, "kubernetes") {
+		t.Error("removeFold() reported removing a missing value")
+	}
+	if subscription.Values("color") != nil {
+		t.Error("Values() returned a list for an unknown kind")
+	}
+
+	if !subscribedToCategory(&models.Subscription{Categories: []string{"dev-ops"}}, models.Category{ID: 5, Slug: "Dev-Ops"}) {
+		t.Error("subscription by slug is not recognized")
+	}
+	if subscribedToCategory(&models.Subscription{Categories: []string{""}}, models.Category{ID: 5}) {
+		t.Error("empty value matches a category without slug")
+	}
+}
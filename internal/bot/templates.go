@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"strings"
+	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/i18n"
+	"webhook_tg_bot/internal/models"
+)
+
+//go:embed templates/*.tmpl
+var embeddedTemplates embed.FS
+
+// MessageData данные, доступные в шаблонах сообщений: все поля ProcessedWebhook
+// ({{.TopicTitle}}, {{.Author}}, {{.Summary}}, {{.URL}}, {{.Tags}}, {{.Category}}, ...) и поля ниже.
+// html/template экранирует подставляемые значения для Telegram HTML
+type MessageData struct {
+	*models.ProcessedWebhook
+	Summary       template.HTML // резюме AI, очищенное до тегов, которые поддерживает Telegram
+	CategoryName  string
+	RolePrefix    string
+	IsPremium     bool
+	IsAccepted    bool
+	PremiumNotice template.HTML // задается в конфигурации и может содержать разметку
+	Highlight     template.HTML // префиксы и упоминания правил наблюдения (watches)
+	Locale        string
+}
+
+var templateFuncs = template.FuncMap{
+	// t возвращает перевод из каталога: {{t .Locale "announcement.link"}}
+	"t": func(locale, key string) template.HTML {
+		return template.HTML(i18n.T(locale, key))
+	},
+	// tags форматирует теги как "#go, #docker" или "нет"
+	"tags": formatTags,
+	"join": strings.Join,
+}
+
+// messageTemplates шаблоны сообщений одного чата
+type messageTemplates struct {
+	announcement *template.Template
+	reply        *template.Template
+}
+
+// Templates шаблоны сообщений по умолчанию и переопределения для чатов
+type Templates struct {
+	defaults     messageTemplates
+	destinations map[string]messageTemplates
+}
+
+// LoadTemplates загружает шаблоны из ANNOUNCEMENT_TEMPLATE/REPLY_TEMPLATE и message_templates чатов.
+// Каждый шаблон проверяется пробной подстановкой при запуске
+func LoadTemplates(cfg *config.Config) (*Templates, error) {
+	cache := make(map[string]*template.Template)
+	var errs []error
+	load := func(kind, path string) *template.Template {
+		key := kind + ":" + path
+		if tmpl, exists := cache[key]; exists {
+			return tmpl
+		}
+		tmpl, err := loadTemplate(kind, path)
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		cache[key] = tmpl
+		return tmpl
+	}
+
+	templates := &Templates{
+		defaults: messageTemplates{
+			announcement: load("announcement", cfg.MessageTemplates.Announcement),
+			reply:        load("reply", cfg.MessageTemplates.Reply),
+		},
+		destinations: make(map[string]messageTemplates),
+	}
+	for _, destination := range cfg.Destinations {
+		templates.destinations[destination.Name] = messageTemplates{
+			announcement: load("announcement", destination.MessageTemplates.Announcement),
+			reply:        load("reply", destination.MessageTemplates.Reply),
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid message templates: %w", errors.Join(errs...))
+	}
+	return templates, nil
+}
+
+// loadTemplate разбирает шаблон из файла или встроенный шаблон, если путь не задан
+func loadTemplate(kind, path string) (*template.Template, error) {
+	var text []byte
+	var err error
+	if path == "" {
+		text, err = embeddedTemplates.ReadFile("templates/" + kind + ".tmpl")
+	} else {
+		text, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s template: %v", kind, err)
+	}
+
+	name := path
+	if name == "" {
+		name = kind
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("%s template: %v", kind, err)
+	}
+
+	sample := &MessageData{
+		ProcessedWebhook: &models.ProcessedWebhook{
+			TopicID:    1,
+			TopicTitle: "Example",
+			Author:     "user",
+			AuthorRole: "user",
+			Category:   "General",
+			CategoryID: 1,
+			Tags:       []string{"example"},
+			Summary:    "Example summary",
+			URL:        "https://forum.example.com/t/example/1",
+		},
+		Summary:       "Example <b>summary</b>",
+		CategoryName:  "General",
+		IsPremium:     true,
+		IsAccepted:    true,
+		PremiumNotice: "notice",
+		Highlight:     "🚨 @user",
+		Locale:        i18n.DefaultLocale,
+	}
+	if err := tmpl.Execute(&bytes.Buffer{}, sample); err != nil {
+		return nil, fmt.Errorf("%s template: %v", kind, err)
+	}
+
+	return tmpl, nil
+}
+
+// forDestination возвращает шаблоны чата или шаблоны по умолчанию
+func (t *Templates) forDestination(destination *config.Destination) messageTemplates {
+	if destination != nil {
+		if templates, exists := t.destinations[destination.Name]; exists {
+			return templates
+		}
+	}
+	return t.defaults
+}
+
+// render подставляет данные в шаблон
+func render(tmpl *template.Template, data *MessageData) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %v", tmpl.Name(), err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,212 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/i18n"
+	"webhook_tg_bot/internal/metrics"
+	"webhook_tg_bot/internal/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// pollingTimeout время ожидания long polling в секундах
+const pollingTimeout = 30
+
+// maxConcurrentUpdates сколько обновлений из webhook обрабатывается одновременно
+const maxConcurrentUpdates = 8
+
+// allowedUpdates типы обновлений, которые обрабатывает бот
+var allowedUpdates = []string{"message", "callback_query"}
+
+// ErrUpdatesBusy все обработчики обновлений заняты; Telegram повторит запрос позже
+var ErrUpdatesBusy = errors.New("too many telegram updates in progress")
+
+// StartUpdates начинает получать обновления Telegram в режиме BOT_UPDATES
+func (tb *TelegramBot) StartUpdates() error {
+	switch tb.config.BotUpdates {
+	case config.BotUpdatesPolling:
+		// getUpdates не работает, пока у бота установлен webhook
+		if _, err := tb.bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+			return fmt.Errorf("failed to delete telegram webhook: %v", err)
+		}
+
+		updates := tb.bot.GetUpdatesChan(tgbotapi.UpdateConfig{Timeout: pollingTimeout, AllowedUpdates: allowedUpdates})
+		go func() {
+			for update := range updates {
+				tb.updatesWG.Add(1)
+				tb.handleUpdate(&update)
+				tb.updatesWG.Done()
+			}
+		}()
+		log.Printf("Receiving Telegram updates via long polling")
+
+	case config.BotUpdatesWebhook:
+		// Библиотека не знает о secret_token, поэтому setWebhook вызывается напрямую
+		params := make(tgbotapi.Params)
+		params["url"] = tb.config.BotWebhookURL
+		params["secret_token"] = tb.config.BotWebhookSecret
+		if err := params.AddInterface("allowed_updates", allowedUpdates); err != nil {
+			return err
+		}
+		if _, err := tb.bot.MakeRequest("setWebhook", params); err != nil {
+			return fmt.Errorf("failed to set telegram webhook: %v", err)
+		}
+		log.Printf("Receiving Telegram updates via webhook %s", tb.config.BotWebhookURL)
+	}
+	return nil
+}
+
+// StopUpdates прекращает long polling и дожидается команд, которые еще выполняются,
+// но не дольше ctx
+func (tb *TelegramBot) StopUpdates(ctx context.Context) {
+	if tb.config.BotUpdates == config.BotUpdatesPolling {
+		tb.bot.StopReceivingUpdates()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		tb.updatesWG.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Printf("Shutdown timeout reached, Telegram updates are still in progress")
+	}
+}
+
+// HandleWebhookUpdate обрабатывает обновление, которое Telegram прислал на webhook.
+// Команда выполняется в фоне: Telegram повторяет запрос, если ответ задерживается.
+// Одновременно выполняется не больше maxConcurrentUpdates команд, сверх этого возвращается ErrUpdatesBusy
+func (tb *TelegramBot) HandleWebhookUpdate(body []byte) error {
+	var update tgbotapi.Update
+	if err := json.Unmarshal(body, &update); err != nil {
+		return fmt.Errorf("failed to decode telegram update: %v", err)
+	}
+
+	select {
+	case tb.updateSlots <- struct{}{}:
+	default:
+		metrics.Inc("telegram_updates_busy")
+		return ErrUpdatesBusy
+	}
+
+	tb.updatesWG.Add(1)
+	go func() {
+		defer func() {
+			<-tb.updateSlots
+			tb.updatesWG.Done()
+		}()
+		tb.handleUpdate(&update)
+	}()
+	return nil
+}
+
+// handleUpdate передает команды из сообщений и нажатия кнопок обработчикам
+func (tb *TelegramBot) handleUpdate(update *tgbotapi.Update) {
+	if update.CallbackQuery != nil {
+		tb.handleCallback(update.CallbackQuery)
+		return
+	}
+
+	message := update.Message
+	if message == nil || !message.IsCommand() {
+		return
+	}
+
+	// В группе команда может быть адресована другому боту: /status@other_bot
+	if _, username, found := strings.Cut(message.CommandWithAt(), "@"); found && !strings.EqualFold(username, tb.bot.Self.UserName) {
+		return
+	}
+
+	tb.handleCommand(message)
+}
+
+// commandScope проверяет права автора команды и возвращает чат, которым он управляет (0 - все чаты).
+// Пользователи из ADMIN_USER_IDS управляют всеми чатами из личных сообщений и конкретным чатом из него самого,
+// администраторы чата из конфигурации (BOT_CHAT_ADMINS) - только своим чатом.
+// В группах не из конфигурации команды не выполняются, чтобы /mute там не отключил анонсы во всех чатах
+func (tb *TelegramBot) commandScope(message *tgbotapi.Message) (int64, bool) {
+	chatID := message.Chat.ID
+	inDestination := len(tb.config.DestinationsInChat(chatID)) > 0
+
+	if message.From != nil && tb.config.IsAdminUser(message.From.ID) {
+		switch {
+		case inDestination:
+			return chatID, true
+		case message.Chat.IsPrivate():
+			return 0, true
+		default:
+			return 0, false
+		}
+	}
+
+	if !tb.config.ChatAdmins || !inDestination {
+		return 0, false
+	}
+	// Анонимный администратор пишет от имени самого чата
+	if message.SenderChat != nil && message.SenderChat.ID == chatID {
+		return chatID, true
+	}
+	if message.From != nil && tb.isChatAdmin(chatID, message.From.ID) {
+		return chatID, true
+	}
+	return 0, false
+}
+
+// isChatAdmin проверяет, что пользователь - создатель или администратор чата
+func (tb *TelegramBot) isChatAdmin(chatID, userID int64) bool {
+	member, err := tb.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+	})
+	if err != nil {
+		log.Printf("Failed to check admin status of user %d in chat %d: %v", userID, chatID, err)
+		return false
+	}
+	return member.IsCreator() || member.IsAdministrator()
+}
+
+// commandLocale возвращает язык ответов на команды в чате
+func (tb *TelegramBot) commandLocale(chatID int64) string {
+	if destinations := tb.config.DestinationsInChat(chatID); len(destinations) > 0 {
+		return tb.localeFor(destinations[0])
+	}
+	return tb.config.DefaultLocale
+}
+
+// reply отвечает на сообщение с командой; ответ не повторяется и не попадает в dead letters.
+// Пустой ответ не отправляется: команда уже ответила сама (например, меню подписки)
+func (tb *TelegramBot) reply(message *tgbotapi.Message, text string) {
+	if text == "" {
+		return
+	}
+	msg := &models.OutgoingMessage{
+		ChatID:           message.Chat.ID,
+		ReplyToMessageID: message.MessageID,
+		Text:             text,
+		ParseMode:        "HTML",
+	}
+	if _, err := tb.send(msg); err != nil {
+		log.Printf("Failed to reply to /%s in chat %d: %v", message.Command(), message.Chat.ID, err)
+		metrics.Inc("command_reply_failures")
+	}
+}
+
+// forbidden сообщает об отсутствии прав; в группах бот молчит, чтобы не мешать участникам
+func (tb *TelegramBot) forbidden(message *tgbotapi.Message, locale string) {
+	metrics.Inc("commands_forbidden")
+	switch {
+	case message.Chat.IsPrivate():
+		tb.reply(message, i18n.T(locale, "command.forbidden"))
+	case message.From != nil && tb.config.IsAdminUser(message.From.ID):
+		// Администратору подсказываем, почему команда не сработала; остальным в группах не отвечаем
+		tb.reply(message, i18n.T(locale, "command.unconfigured_chat"))
+	}
+}
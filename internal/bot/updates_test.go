@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"testing"
+	"webhook_tg_bot/internal/config"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestCommandScope(t *testing.T) {
+	const (
+		adminID    = 1
+		userID     = 2
+		configured = -100
+		foreign    = -200
+	)
+	tb := &TelegramBot{config: &config.Config{
+		AdminUserIDs: []int64{adminID},
+		ChatAdmins:   true,
+		Destinations: []config.Destination{{Name: "main", ChatID: configured}},
+	}}
+
+	message := func(chatID int64, chatType string, from int64) *tgbotapi.Message {
+		return &tgbotapi.Message{
+			Chat: &tgbotapi.Chat{ID: chatID, Type: chatType},
+			From: &tgbotapi.User{ID: from},
+		}
+	}
+	anonymous := message(configured, "supergroup", 0)
+	anonymous.From = nil
+	anonymous.SenderChat = &tgbotapi.Chat{ID: configured}
+
+	tests := []struct {
+		name        string
+		message     *tgbotapi.Message
+		wantScope   int64
+		wantAllowed bool
+	}{
+		{"admin in private chat manages all chats", message(adminID, "private", adminID), 0, true},
+		{"admin in configured chat manages it", message(configured, "supergroup", adminID), configured, true},
+		{"admin in unknown group is rejected", message(foreign, "supergroup", adminID), 0, false},
+		{"user in private chat is rejected", message(userID, "private", userID), 0, false},
+		{"user in unknown group is rejected", message(foreign, "group", userID), 0, false},
+		{"anonymous admin of configured chat", anonymous, configured, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope, allowed := tb.commandScope(tt.message)
+			if scope != tt.wantScope || allowed != tt.wantAllowed {
+				t.Errorf("commandScope() = (%d, %v), want (%d, %v)", scope, allowed, tt.wantScope, tt.wantAllowed)
+			}
+		})
+	}
+}
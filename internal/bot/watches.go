@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"log"
+	"strings"
+	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/metrics"
+	"webhook_tg_bot/internal/models"
+)
+
+// watchTarget чат и thread, в которые уходит анонс
+type watchTarget struct {
+	chatID   int64
+	threadID int
+}
+
+// watchDestinations возвращает чаты правил наблюдения, под которые подходит тема.
+// Чат пропускается, если тема и так уходит туда обычным анонсом: правило выделит этот анонс
+func (tb *TelegramBot) watchDestinations(processed *models.ProcessedWebhook, destinations []*config.Destination) []*config.Destination {
+	watches := tb.config.MatchingWatches(processed)
+	if len(watches) == 0 {
+		return nil
+	}
+
+	taken := make(map[watchTarget]bool)
+	for _, destination := range destinations {
+		route := destination.ResolveRoute(processed)
+		taken[watchTarget{route.ChatID, route.Thread.ID}] = true
+	}
+
+	var result []*config.Destination
+	for _, watch := range watches {
+		log.Printf("Topic %d matches watch rule %s", processed.TopicID, watch.Name)
+		metrics.Inc("watch_matches")
+
+		if watch.Destination == nil {
+			continue
+		}
+		target := watchTarget{watch.Destination.ChatID, watch.Destination.Thread.ID}
+		if taken[target] {
+			continue
+		}
+		taken[target] = true
+		result = append(result, watch.Destination)
+	}
+	return result
+}
+
+// highlightFor собирает префиксы и упоминания правил наблюдения для анонса в чате destination.
+// Личные сообщения подписчикам и чаты, которых больше нет в конфигурации, не выделяются
+func (tb *TelegramBot) highlightFor(destination *config.Destination, processed *models.ProcessedWebhook) template.HTML {
+	if destination == nil || tb.config.GetDestination(destination.Name) == nil {
+		return ""
+	}
+
+	var lines []string
+	route := destination.ResolveRoute(processed)
+	for _, watch := range tb.config.MatchingWatches(processed) {
+		if !watch.Highlights(route.ChatID, route.Thread.ID) {
+			continue
+		}
+
+		parts := make([]string, 0, len(watch.Mentions)+1)
+		if watch.Prefix != "" {
+			parts = append(parts, sanitizeHTML(watch.Prefix))
+		}
+		for _, mention := range watch.Mentions {
+			parts = append(parts, mentionHTML(mention))
+		}
+		if len(parts) > 0 {
+			lines = append(lines, strings.Join(parts, " "))
+		}
+	}
+	return template.HTML(strings.Join(lines, "\n"))
+}
+
+// mentionHTML упоминание пользователя: @username или ссылка tg://user для пользователей без username
+func mentionHTML(mention config.Mention) string {
+	if mention.Username != "" {
+		return "@" + html.EscapeString(mention.Username)
+	}
+
+	name := mention.Name
+	if name == "" {
+		name = fmt.Sprint(mention.UserID)
+	}
+	return fmt.Sprintf(`<a href="tg://user?id=%d">%s</a>`, mention.UserID, html.EscapeString(name))
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Типы кнопок под анонсом
+const (
+	ButtonTopic     = "topic"     // открыть тему
+	ButtonCategory  = "category"  // открыть категорию
+	ButtonReply     = "reply"     // перейти к концу темы, чтобы ответить
+	ButtonSubscribe = "subscribe" // оформить подписку; только для платных разделов
+	ButtonURL       = "url"       // произвольная ссылка
+)
+
+var buttonTypes = []string{ButtonTopic, ButtonCategory, ButtonReply, ButtonSubscribe, ButtonURL}
+
+// Button кнопка inline-клавиатуры под анонсом
+type Button struct {
+	Type string
+	Text string // пусто - текст из каталога сообщений
+	URL  string // для subscribe и url
+	Row  int    // кнопки с одинаковым номером ряда стоят рядом
+}
+
+// validate проверяет кнопку и подставляет ссылку на подписку по умолчанию
+func (b *Button) validate(subscribeURL string) error {
+	if !containsFold(buttonTypes, b.Type) {
+		return fmt.Errorf("unknown button type %q (expected one of %v)", b.Type, buttonTypes)
+	}
+	b.Type = strings.ToLower(b.Type)
+
+	switch b.Type {
+	case ButtonSubscribe:
+		if b.URL == "" {
+			b.URL = subscribeURL
+		}
+		if b.URL == "" {
+			return fmt.Errorf("subscribe button requires url or SUBSCRIBE_URL")
+		}
+	case ButtonURL:
+		if b.URL == "" || b.Text == "" {
+			return fmt.Errorf("url button requires text and url")
+		}
+	}
+	return nil
+}
+
+// loadEnvButtons читает кнопки по умолчанию из ANNOUNCEMENT_BUTTONS (например, "topic,category,subscribe")
+func loadEnvButtons(subscribeURL string) ([]Button, error) {
+	var buttons []Button
+	for _, item := range strings.Split(os.Getenv("ANNOUNCEMENT_BUTTONS"), ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		button := Button{Type: item}
+		if err := button.validate(subscribeURL); err != nil {
+			return nil, fmt.Errorf("invalid ANNOUNCEMENT_BUTTONS: %v", err)
+		}
+		buttons = append(buttons, button)
+	}
+	return buttons, nil
+}
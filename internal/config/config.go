@@ -5,25 +5,123 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
+	"webhook_tg_bot/internal/i18n"
 )
 
+// Режимы публикации в thread
+const (
+	// ThreadModeTopic - тема форума в супергруппе (message_thread_id)
+	ThreadModeTopic = "topic"
+	// ThreadModeReply - ответ на "якорное" сообщение (reply_to_message_id)
+	ThreadModeReply = "reply"
+)
+
+// ThreadTarget куда публиковать сообщение внутри чата
+type ThreadTarget struct {
+	ID   int    // 0 - без thread
+	Mode string // ThreadModeTopic или ThreadModeReply
+}
+
+// AIProviderConfig провайдер в цепочке генерации резюме и его таймаут
+type AIProviderConfig struct {
+	Name    string
+	Timeout time.Duration
+}
+
+// MessageTemplates пути к файлам шаблонов сообщений; пусто - встроенный шаблон
+type MessageTemplates struct {
+	Announcement string
+	Reply        string
+}
+
+// withDefaults дополняет незаданные шаблоны шаблонами по умолчанию
+func (t MessageTemplates) withDefaults(defaults MessageTemplates) MessageTemplates {
+	if t.Announcement == "" {
+		t.Announcement = defaults.Announcement
+	}
+	if t.Reply == "" {
+		t.Reply = defaults.Reply
+	}
+	return t
+}
+
+// PromptRule шаблон запроса к AI для набора категорий
+type PromptRule struct {
+	Categories []int
+	Template   string
+}
+
+// ReplyRule правило уведомлений об ответах для набора категорий
+type ReplyRule struct {
+	Categories   []int // пусто - правило для всех категорий без собственного правила
+	StaffOnly    bool  // только ответы администраторов, модераторов и staff
+	AcceptedOnly bool  // только ответы, отмеченные как решение
+	MinPosts     int   // только темы, в которых не меньше MinPosts постов
+}
+
 type Config struct {
 	// Telegram settings
-	TelegramBotToken string
-	TelegramChatID   int64
-	TelegramThreadID int
+	TelegramBotToken   string
+	TelegramChatID     int64
+	TelegramThreadID   int
+	TelegramThreadMode string // режим по умолчанию для всех thread'ов
+
+	// Язык сообщений и резюме по умолчанию (DEFAULT_LOCALE)
+	DefaultLocale string
+
+	// Шаблоны сообщений по умолчанию (ANNOUNCEMENT_TEMPLATE, REPLY_TEMPLATE)
+	MessageTemplates MessageTemplates
+
+	// Retries for failed sends (after the last one the message goes to the dead-letter store)
+	TelegramMaxRetries int
+	TelegramRetryDelay time.Duration // базовая задержка, удваивается с каждой попыткой
+
+	// Routing rules of the main chat (config file routes, or TELEGRAM_THREAD_ID_N/THREAD_CATEGORIES_N as a fallback)
+	Routes []Route
+
+	// Chats that receive announcements (config file destinations, or the main chat)
+	Destinations []Destination
 
-	// Additional threads mapping
-	CategoryThreads map[int]int // category_id -> thread_id
+	// Keyword and regex watch rules from the config file, applied regardless of category
+	Watches []WatchRule
 
 	// Webhook settings
 	WebhookSecret string
 	WebhookPort   string
 	WebhookPath   string
 
+	// Telegram updates for admin commands (BOT_UPDATES): off, polling or webhook
+	BotUpdates       string
+	BotWebhookURL    string // публичный адрес для setWebhook
+	BotWebhookPath   string // путь из BOT_WEBHOOK_URL на сервере вебхуков
+	BotWebhookSecret string // secret_token, которым Telegram подписывает запросы
+	AdminUserIDs     []int64
+	ChatAdmins       bool // администраторы чатов из конфигурации могут управлять ботом в своем чате
+
+	// Personal DM subscriptions (SUBSCRIPTIONS) and their delivery rate, messages per second
+	Subscriptions    bool
+	SubscriptionRate int
+
+	// Time to finish queued notifications on shutdown
+	ShutdownTimeout time.Duration
+
 	// AI settings
-	OpenAIAPIKey string
-	OpenAIModel  string
+	AIProvider      string // openai, openai-compatible, anthropic, none
+	AIProviders     []AIProviderConfig
+	AITimeout       time.Duration
+	AIBaseURL       string // endpoint openai-compatible (Ollama, LM Studio, vLLM, OpenRouter)
+	AIAPIKey        string // ключ openai-compatible
+	AIModel         string // модель openai-compatible
+	OpenAIAPIKey    string
+	OpenAIModel     string
+	AnthropicAPIKey string
+	AnthropicModel  string
+
+	// Шаблоны запросов к AI
+	PromptsDir     string // каталог с *.tmpl; встроенный шаблон называется default
+	PromptTemplate string // шаблон по умолчанию
+	PromptRules    []PromptRule
 
 	// Premium categories (paid sections)
 	PremiumCategories []int
@@ -39,9 +137,48 @@ type Config struct {
 
 	// Base URL for topics
 	BaseURL string
+
+	// Storage settings
+	StorageType string // memory или bolt
+	StoragePath string // путь к файлу базы для bolt
+	TopicTTL    time.Duration
+
+	// Сколько хранить отправленные анонсы для правок, снятия и ответов (по последнему обновлению); 0 - бессрочно
+	AnnouncementTTL time.Duration
+
+	// Delivery of incomplete topics (only topic or only first post arrived)
+	IncompleteGracePeriod time.Duration // 0 - не отправлять неполные темы
+
+	// Asynchronous processing
+	QueueSize   int
+	WorkerCount int
+
+	// What to do with an announcement when its topic is deleted, unlisted or moved
+	// to an ignored category: "delete" the message or "stub" (replace with a notice)
+	RemovalMode string
+
+	// Send the first image of the post as a photo with the announcement as its caption
+	AnnouncementPhotos bool
+	ImageHosts         []string // хосты CDN/S3, откуда кроме BASE_URL можно скачивать картинки
+
+	// Inline keyboard under announcements (ANNOUNCEMENT_BUTTONS) and the VIP link for premium categories
+	AnnouncementButtons []Button
+	SubscribeURL        string
+
+	// Reply notifications (opt-in)
+	ReplyNotifications bool
+	ReplyRules         []ReplyRule
+
+	// Deduplication window for X-Discourse-Event-Id and announced topics (0 - disabled)
+	DedupWindow time.Duration
+
+	// Discourse API (optional, used to fetch missing first posts)
+	DiscourseAPIKey      string
+	DiscourseAPIUsername string
 }
 
-func Load() (*Config, error) {
+// Load загружает конфигурацию из переменных окружения и, если указан, YAML-файла
+func Load(configFile string) (*Config, error) {
 	cfg := &Config{}
 
 	// Telegram settings
@@ -69,33 +206,29 @@ func Load() (*Config, error) {
 		cfg.TelegramThreadID = threadID
 	}
 
-	// Загружаем дополнительные thread'ы
-	cfg.CategoryThreads = make(map[int]int)
-	for i := 1; i <= 5; i++ {
-		threadIDKey := fmt.Sprintf("TELEGRAM_THREAD_ID_%d", i)
-		categoriesKey := fmt.Sprintf("THREAD_CATEGORIES_%d", i)
+	if cfg.TelegramMaxRetries, err = parseIntAtLeast("TELEGRAM_MAX_RETRIES", 3, 0); err != nil {
+		return nil, err
+	}
+	if cfg.TelegramRetryDelay, err = parseDuration("TELEGRAM_RETRY_DELAY", 1*time.Second); err != nil {
+		return nil, err
+	}
 
-		threadIDStr := os.Getenv(threadIDKey)
-		categoriesStr := os.Getenv(categoriesKey)
+	if cfg.TelegramThreadMode, err = parseThreadMode("TELEGRAM_THREAD_MODE", ThreadModeTopic); err != nil {
+		return nil, err
+	}
 
-		if threadIDStr != "" && categoriesStr != "" {
-			threadID, err := strconv.Atoi(threadIDStr)
-			if err != nil {
-				return nil, fmt.Errorf("invalid %s: %v", threadIDKey, err)
-			}
+	// Язык сообщений и резюме по умолчанию; у каждого чата может быть свой
+	cfg.DefaultLocale = i18n.Normalize(os.Getenv("DEFAULT_LOCALE"))
+	if cfg.DefaultLocale == "" {
+		cfg.DefaultLocale = i18n.DefaultLocale
+	}
+	if !i18n.Supported(cfg.DefaultLocale) {
+		return nil, fmt.Errorf("unsupported DEFAULT_LOCALE %q (supported: %s)", cfg.DefaultLocale, strings.Join(i18n.Locales(), ", "))
+	}
 
-			categories := strings.Split(categoriesStr, ",")
-			for _, catStr := range categories {
-				catStr = strings.TrimSpace(catStr)
-				if catStr != "" {
-					categoryID, err := strconv.Atoi(catStr)
-					if err != nil {
-						return nil, fmt.Errorf("invalid category ID '%s' in %s: %v", catStr, categoriesKey, err)
-					}
-					cfg.CategoryThreads[categoryID] = threadID
-				}
-			}
-		}
+	cfg.MessageTemplates = MessageTemplates{
+		Announcement: os.Getenv("ANNOUNCEMENT_TEMPLATE"),
+		Reply:        os.Getenv("REPLY_TEMPLATE"),
 	}
 
 	// Webhook settings
@@ -114,12 +247,62 @@ func Load() (*Config, error) {
 		cfg.WebhookPath = "/webhook"
 	}
 
+	if cfg.ShutdownTimeout, err = parseDuration("SHUTDOWN_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+
+	// Команды администраторов в Telegram
+	if err := cfg.loadBotUpdates(); err != nil {
+		return nil, err
+	}
+
+	// Личные подписки управляются командами, поэтому требуют получения обновлений
+	cfg.Subscriptions = strings.EqualFold(os.Getenv("SUBSCRIPTIONS"), "true")
+	if cfg.Subscriptions && cfg.BotUpdates == BotUpdatesOff {
+		return nil, fmt.Errorf("SUBSCRIPTIONS=true requires BOT_UPDATES=polling or webhook")
+	}
+	// Telegram ограничивает рассылку примерно 30 сообщениями в секунду
+	if cfg.SubscriptionRate, err = parseIntAtLeast("SUBSCRIPTION_RATE", 20, 1); err != nil {
+		return nil, err
+	}
+	if cfg.SubscriptionRate > 30 {
+		return nil, fmt.Errorf("SUBSCRIPTION_RATE must not exceed 30 messages per second")
+	}
+
 	// AI settings
 	cfg.OpenAIAPIKey = os.Getenv("OPENAI_API_KEY")
 	cfg.OpenAIModel = os.Getenv("OPENAI_MODEL")
 	if cfg.OpenAIModel == "" {
 		cfg.OpenAIModel = "gpt-4.1-nano"
 	}
+	cfg.AnthropicAPIKey = os.Getenv("ANTHROPIC_API_KEY")
+	cfg.AnthropicModel = os.Getenv("ANTHROPIC_MODEL")
+	if cfg.AnthropicModel == "" {
+		cfg.AnthropicModel = "claude-3-5-haiku-latest"
+	}
+	cfg.AIBaseURL = os.Getenv("AI_BASE_URL")
+	cfg.AIAPIKey = os.Getenv("AI_API_KEY")
+	cfg.AIModel = os.Getenv("AI_MODEL")
+
+	// Без явного выбора используем OpenAI, если есть ключ, иначе работаем без AI
+	cfg.AIProvider = strings.ToLower(os.Getenv("AI_PROVIDER"))
+	if cfg.AIProvider == "" {
+		cfg.AIProvider = "none"
+		if cfg.OpenAIAPIKey != "" {
+			cfg.AIProvider = "openai"
+		}
+	}
+	cfg.PromptsDir = os.Getenv("PROMPTS_DIR")
+	cfg.PromptTemplate = os.Getenv("PROMPT_TEMPLATE")
+	if cfg.PromptTemplate == "" {
+		cfg.PromptTemplate = "default"
+	}
+	if cfg.AITimeout, err = parseDuration("AI_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.AIProviders, err = parseAIProviders("AI_PROVIDERS", cfg.AIProvider, cfg.AITimeout); err != nil {
+		return nil, err
+	}
 
 	// Premium categories
 	premiumCategoriesStr := os.Getenv("PREMIUM_CATEGORIES")
@@ -171,9 +354,247 @@ func Load() (*Config, error) {
 		cfg.BaseURL = "https://your-forum.com"
 	}
 
+	// Storage settings
+	cfg.StorageType = strings.ToLower(os.Getenv("STORAGE_TYPE"))
+	if cfg.StorageType == "" {
+		cfg.StorageType = "memory"
+	}
+	if cfg.StorageType != "memory" && cfg.StorageType != "bolt" {
+		return nil, fmt.Errorf("invalid STORAGE_TYPE: %s (expected memory or bolt)", cfg.StorageType)
+	}
+
+	cfg.StoragePath = os.Getenv("STORAGE_PATH")
+	if cfg.StoragePath == "" {
+		cfg.StoragePath = "data/bot.db"
+	}
+
+	if cfg.TopicTTL, err = parseDuration("TOPIC_TTL", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.AnnouncementTTL, err = parseDuration("ANNOUNCEMENT_TTL", 30*24*time.Hour); err != nil {
+		return nil, err
+	}
+
+	// Incomplete topics: по умолчанию неполные темы отбрасываются, отправка включается явно
+	if cfg.IncompleteGracePeriod, err = parseDuration("INCOMPLETE_GRACE_PERIOD", 0); err != nil {
+		return nil, err
+	}
+	if cfg.IncompleteGracePeriod > 0 && cfg.IncompleteGracePeriod >= cfg.TopicTTL {
+		return nil, fmt.Errorf("INCOMPLETE_GRACE_PERIOD (%s) must be shorter than TOPIC_TTL (%s)", cfg.IncompleteGracePeriod, cfg.TopicTTL)
+	}
+
+	// Asynchronous processing
+	if cfg.QueueSize, err = parseIntAtLeast("QUEUE_SIZE", 100, 1); err != nil {
+		return nil, err
+	}
+	if cfg.WorkerCount, err = parseIntAtLeast("WORKER_COUNT", 4, 1); err != nil {
+		return nil, err
+	}
+
+	// Announcement removal
+	cfg.RemovalMode = strings.ToLower(os.Getenv("ANNOUNCEMENT_REMOVAL_MODE"))
+	if cfg.RemovalMode == "" {
+		cfg.RemovalMode = "delete"
+	}
+	if cfg.RemovalMode != "delete" && cfg.RemovalMode != "stub" {
+		return nil, fmt.Errorf("invalid ANNOUNCEMENT_REMOVAL_MODE: %s (expected delete or stub)", cfg.RemovalMode)
+	}
+
+	// Картинки в анонсах включаются явно: иначе анонсы существующих установок стали бы фотографиями
+	cfg.AnnouncementPhotos = strings.EqualFold(os.Getenv("ANNOUNCEMENT_PHOTOS"), "true")
+	for _, host := range strings.Split(os.Getenv("IMAGE_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			cfg.ImageHosts = append(cfg.ImageHosts, host)
+		}
+	}
+
+	// Кнопки под анонсом
+	cfg.SubscribeURL = os.Getenv("SUBSCRIBE_URL")
+	if cfg.AnnouncementButtons, err = loadEnvButtons(cfg.SubscribeURL); err != nil {
+		return nil, err
+	}
+
+	// Reply notifications
+	cfg.ReplyNotifications = strings.EqualFold(os.Getenv("REPLY_NOTIFICATIONS"), "true")
+	if cfg.ReplyRules, err = loadReplyRules(); err != nil {
+		return nil, err
+	}
+	if cfg.ReplyNotifications && len(cfg.ReplyRules) == 0 {
+		// Без правил уведомляем обо всех ответах
+		cfg.ReplyRules = []ReplyRule{{}}
+	}
+
+	// Deduplication
+	if cfg.DedupWindow, err = parseDuration("DEDUP_WINDOW", 24*time.Hour); err != nil {
+		return nil, err
+	}
+
+	// Discourse API
+	cfg.DiscourseAPIKey = os.Getenv("DISCOURSE_API_KEY")
+	cfg.DiscourseAPIUsername = os.Getenv("DISCOURSE_API_USERNAME")
+	if cfg.DiscourseAPIUsername == "" {
+		cfg.DiscourseAPIUsername = "system"
+	}
+
+	// Маршруты из файла конфигурации, иначе из переменных окружения
+	if configFile != "" {
+		if err := cfg.loadFile(configFile); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.Routes) == 0 {
+		if cfg.Routes, err = loadEnvRoutes(cfg.TelegramThreadMode); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.Destinations) == 0 {
+		cfg.Destinations = []Destination{cfg.defaultDestination()}
+	}
+	for i := range cfg.Destinations {
+		if cfg.Destinations[i].Locale == "" {
+			cfg.Destinations[i].Locale = cfg.DefaultLocale
+		}
+		cfg.Destinations[i].MessageTemplates = cfg.Destinations[i].MessageTemplates.withDefaults(cfg.MessageTemplates)
+	}
+	for i := range cfg.Watches {
+		if destination := cfg.Watches[i].Destination; destination != nil {
+			if destination.Locale == "" {
+				destination.Locale = cfg.DefaultLocale
+			}
+			destination.MessageTemplates = cfg.MessageTemplates
+		}
+	}
+
 	return cfg, nil
 }
 
+// loadReplyRules читает правила REPLY_RULE_1_*, REPLY_RULE_2_*, ... до первого пропуска
+func loadReplyRules() ([]ReplyRule, error) {
+	var rules []ReplyRule
+
+	for i := 1; ; i++ {
+		prefix := fmt.Sprintf("REPLY_RULE_%d_", i)
+		categoriesStr := os.Getenv(prefix + "CATEGORIES")
+		staffOnlyStr := os.Getenv(prefix + "STAFF_ONLY")
+		acceptedOnlyStr := os.Getenv(prefix + "ACCEPTED_ONLY")
+		minPostsStr := os.Getenv(prefix + "MIN_POSTS")
+
+		if categoriesStr == "" && staffOnlyStr == "" && acceptedOnlyStr == "" && minPostsStr == "" {
+			return rules, nil
+		}
+
+		rule := ReplyRule{
+			StaffOnly:    strings.EqualFold(staffOnlyStr, "true"),
+			AcceptedOnly: strings.EqualFold(acceptedOnlyStr, "true"),
+		}
+
+		for _, catStr := range strings.Split(categoriesStr, ",") {
+			catStr = strings.TrimSpace(catStr)
+			if catStr == "" {
+				continue
+			}
+			categoryID, err := strconv.Atoi(catStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid category ID '%s' in %sCATEGORIES: %v", catStr, prefix, err)
+			}
+			rule.Categories = append(rule.Categories, categoryID)
+		}
+
+		if minPostsStr != "" {
+			minPosts, err := strconv.Atoi(minPostsStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %sMIN_POSTS: %v", prefix, err)
+			}
+			rule.MinPosts = minPosts
+		}
+
+		rules = append(rules, rule)
+	}
+}
+
+// parseThreadMode читает режим публикации в thread (topic или reply)
+func parseThreadMode(key, defaultValue string) (string, error) {
+	mode := strings.ToLower(os.Getenv(key))
+	switch mode {
+	case "":
+		return defaultValue, nil
+	case ThreadModeTopic, ThreadModeReply:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid %s: %s (expected %s or %s)", key, mode, ThreadModeTopic, ThreadModeReply)
+	}
+}
+
+// parseAIProviders разбирает цепочку провайдеров вида "openai:10s,anthropic,local".
+// Без явной цепочки используется единственный провайдер из AI_PROVIDER
+func parseAIProviders(key, defaultProvider string, defaultTimeout time.Duration) ([]AIProviderConfig, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return []AIProviderConfig{{Name: defaultProvider, Timeout: defaultTimeout}}, nil
+	}
+
+	var providers []AIProviderConfig
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		provider := AIProviderConfig{Name: strings.ToLower(item), Timeout: defaultTimeout}
+		if name, timeout, found := strings.Cut(item, ":"); found {
+			parsed, err := time.ParseDuration(strings.TrimSpace(timeout))
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("invalid timeout for AI provider %q in %s: %s", name, key, timeout)
+			}
+			provider.Name = strings.ToLower(strings.TrimSpace(name))
+			provider.Timeout = parsed
+		}
+		providers = append(providers, provider)
+	}
+
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("%s is set but contains no providers", key)
+	}
+	return providers, nil
+}
+
+// parseDuration читает длительность из переменной окружения ("90s", "5m", "0" - отключено)
+func parseDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	if value == "0" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", key, err)
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("invalid %s: must not be negative", key)
+	}
+	return duration, nil
+}
+
+// parseIntAtLeast читает целое число не меньше min из переменной окружения
+func parseIntAtLeast(key string, defaultValue, min int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", key, err)
+	}
+	if number < min {
+		return 0, fmt.Errorf("invalid %s: must be at least %d", key, min)
+	}
+	return number, nil
+}
+
 // IsPremiumCategory проверяет, является ли категория платной
 func (cfg *Config) IsPremiumCategory(categoryID int) bool {
 	for _, premiumID := range cfg.PremiumCategories {
@@ -208,12 +629,74 @@ func (cfg *Config) ShouldMonitorCategory(categoryID int) bool {
 	return false
 }
 
-// GetThreadIDForCategory возвращает thread ID для указанной категории
-func (cfg *Config) GetThreadIDForCategory(categoryID int) int {
-	if threadID, exists := cfg.CategoryThreads[categoryID]; exists {
-		return threadID
+// ReplyRuleForCategory возвращает правило уведомлений об ответах для категории:
+// сначала правило, где категория указана явно, затем общее правило без категорий
+func (cfg *Config) ReplyRuleForCategory(categoryID int) *ReplyRule {
+	var fallback *ReplyRule
+	for i := range cfg.ReplyRules {
+		rule := &cfg.ReplyRules[i]
+		if len(rule.Categories) == 0 {
+			if fallback == nil {
+				fallback = rule
+			}
+			continue
+		}
+		for _, id := range rule.Categories {
+			if id == categoryID {
+				return rule
+			}
+		}
+	}
+	return fallback
+}
+
+// ShouldNotifyReply проверяет, нужно ли анонсировать ответ
+func (cfg *Config) ShouldNotifyReply(categoryID int, isStaff, isAccepted bool, topicPostsCount int) bool {
+	if !cfg.ReplyNotifications {
+		return false
+	}
+
+	rule := cfg.ReplyRuleForCategory(categoryID)
+	if rule == nil {
+		return false
+	}
+	if rule.StaffOnly && !isStaff {
+		return false
+	}
+	if rule.AcceptedOnly && !isAccepted {
+		return false
+	}
+	if rule.MinPosts > 0 && topicPostsCount < rule.MinPosts {
+		return false
+	}
+	return true
+}
+
+// PromptFor возвращает имя шаблона запроса к AI: шаблон чата важнее правил по категориям
+func (cfg *Config) PromptFor(destination *Destination, categoryID int) string {
+	if destination != nil && destination.Prompt != "" {
+		return destination.Prompt
+	}
+	for _, rule := range cfg.PromptRules {
+		if containsInt(rule.Categories, categoryID) {
+			return rule.Template
+		}
+	}
+	return cfg.PromptTemplate
+}
+
+// PromptNames возвращает все шаблоны, на которые ссылается конфигурация
+func (cfg *Config) PromptNames() []string {
+	names := []string{cfg.PromptTemplate}
+	for _, rule := range cfg.PromptRules {
+		names = append(names, rule.Template)
+	}
+	for _, destination := range cfg.Destinations {
+		if destination.Prompt != "" {
+			names = append(names, destination.Prompt)
+		}
 	}
-	return cfg.TelegramThreadID // возвращаем дефолтный thread ID
+	return names
 }
 
 // ShouldIgnoreUser проверяет, нужно ли игнорировать пользователя
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAIProviders(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []AIProviderConfig
+		wantErr bool
+	}{
+		{"fallback to AI_PROVIDER", "", []AIProviderConfig{{Name: "openai", Timeout: 30 * time.Second}}, false},
+		{"single provider", "anthropic", []AIProviderConfig{{Name: "anthropic", Timeout: 30 * time.Second}}, false},
+		{
+			"chain with timeouts",
+			"OpenAI:10s, anthropic ,local:1m",
+			[]AIProviderConfig{
+				{Name: "openai", Timeout: 10 * time.Second},
+				{Name: "anthropic", Timeout: 30 * time.Second},
+				{Name: "local", Timeout: time.Minute},
+			},
+			false,
+		},
+		{"empty items skipped", "openai,,local", []AIProviderConfig{{Name: "openai", Timeout: 30 * time.Second}, {Name: "local", Timeout: 30 * time.Second}}, false},
+		{"invalid timeout", "openai:soon", nil, true},
+		{"zero timeout", "openai:0s", nil, true},
+		{"only separators", " , ", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AI_PROVIDERS", tt.value)
+			got, err := parseAIProviders("AI_PROVIDERS", "openai", 30*time.Second)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAIProviders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAIProviders() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
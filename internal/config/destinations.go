@@ -0,0 +1,154 @@
+package config
+
+import (
+	"sort"
+	"webhook_tg_bot/internal/i18n"
+	"webhook_tg_bot/internal/models"
+)
+
+// Destination чат, в который дублируются анонсы, со своими фильтрами и маршрутизацией
+type Destination struct {
+	Name   string
+	ChatID int64
+	Thread ThreadTarget // thread по умолчанию
+	Routes []Route      // маршрутизация по thread'ам внутри чата
+
+	PremiumNotice     bool
+	PremiumNoticeText string
+
+	Photos  bool     // отправлять первую картинку поста фотографией с анонсом в подписи
+	Buttons []Button // кнопки под анонсом
+
+	Prompt string // шаблон запроса к AI; пусто - по правилам категорий
+	Locale string // язык анонсов и резюме; пусто - DEFAULT_LOCALE
+
+	MessageTemplates MessageTemplates // шаблоны сообщений; незаданные берутся из общих настроек
+
+	// Фильтры; пустой список не ограничивает выбор
+	Categories        []int
+	IgnoredCategories []int
+	Tags              []string
+	IgnoredTags       []string
+}
+
+// Accepts проверяет, нужно ли отправлять тему в этот чат
+func (d *Destination) Accepts(processed *models.ProcessedWebhook) bool {
+	if !d.AcceptsCategory(processed.CategoryID) {
+		return false
+	}
+	if containsAnyFold(d.IgnoredTags, processed.Tags) {
+		return false
+	}
+	if len(d.Tags) > 0 && !containsAnyFold(d.Tags, processed.Tags) {
+		return false
+	}
+	return true
+}
+
+// AcceptsCategory проверяет фильтры чата по категориям
+func (d *Destination) AcceptsCategory(categoryID int) bool {
+	if containsInt(d.IgnoredCategories, categoryID) {
+		return false
+	}
+	return len(d.Categories) == 0 || containsInt(d.Categories, categoryID)
+}
+
+// ResolveRoute возвращает первое подходящее правило с заполненным чатом,
+// либо маршрут по умолчанию для этого чата
+func (d *Destination) ResolveRoute(processed *models.ProcessedWebhook) Route {
+	for _, route := range d.Routes {
+		if route.Matches(processed) {
+			if route.ChatID == 0 {
+				route.ChatID = d.ChatID
+			}
+			return route
+		}
+	}
+
+	return Route{
+		Name:   "default",
+		ChatID: d.ChatID,
+		Thread: d.Thread,
+	}
+}
+
+// PremiumNoticeFor возвращает уведомление о платном разделе или пустую строку
+func (d *Destination) PremiumNoticeFor(isPremium bool) string {
+	if !isPremium || !d.PremiumNotice {
+		return ""
+	}
+	if d.PremiumNoticeText != "" {
+		return d.PremiumNoticeText
+	}
+	return i18n.T(d.Locale, "premium.notice")
+}
+
+// ButtonRows раскладывает кнопки по рядам в порядке номеров рядов
+func (d *Destination) ButtonRows() [][]Button {
+	buttons := append([]Button(nil), d.Buttons...)
+	sort.SliceStable(buttons, func(i, j int) bool {
+		return buttons[i].Row < buttons[j].Row
+	})
+
+	var rows [][]Button
+	for i, button := range buttons {
+		if i == 0 || button.Row != buttons[i-1].Row {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], button)
+	}
+	return rows
+}
+
+// MatchingDestinations возвращает чаты, в которые нужно отправить тему
+func (cfg *Config) MatchingDestinations(processed *models.ProcessedWebhook) []*Destination {
+	var result []*Destination
+	for i := range cfg.Destinations {
+		if cfg.Destinations[i].Accepts(processed) {
+			result = append(result, &cfg.Destinations[i])
+		}
+	}
+	return result
+}
+
+// AcceptsCategory сообщает, анонсируются ли темы категории хотя бы в один чат
+func (cfg *Config) AcceptsCategory(categoryID int) bool {
+	if !cfg.ShouldMonitorCategory(categoryID) {
+		return false
+	}
+	for i := range cfg.Destinations {
+		if cfg.Destinations[i].AcceptsCategory(categoryID) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetDestination возвращает чат по имени, включая чаты правил наблюдения (nil, если такого нет)
+func (cfg *Config) GetDestination(name string) *Destination {
+	for i := range cfg.Destinations {
+		if cfg.Destinations[i].Name == name {
+			return &cfg.Destinations[i]
+		}
+	}
+	for i := range cfg.Watches {
+		if destination := cfg.Watches[i].Destination; destination != nil && destination.Name == name {
+			return destination
+		}
+	}
+	return nil
+}
+
+// defaultDestination основной чат из TELEGRAM_CHAT_ID и маршрутов верхнего уровня
+func (cfg *Config) defaultDestination() Destination {
+	return Destination{
+		Name:          "main",
+		ChatID:        cfg.TelegramChatID,
+		Thread:        ThreadTarget{ID: cfg.TelegramThreadID, Mode: cfg.TelegramThreadMode},
+		Routes:        cfg.Routes,
+		PremiumNotice: true,
+		Photos:        cfg.AnnouncementPhotos,
+		Buttons:       cfg.AnnouncementButtons,
+		Locale:        cfg.DefaultLocale,
+	}
+}
@@ -0,0 +1,365 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"webhook_tg_bot/internal/i18n"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig структура YAML-файла конфигурации (CONFIG_FILE или флаг -config)
+type fileConfig struct {
+	Routes       []fileRoute       `yaml:"routes"`
+	Destinations []fileDestination `yaml:"destinations"`
+	Watches      []fileWatch       `yaml:"watches"`
+	Prompts      filePrompts       `yaml:"prompts"`
+
+	MessageTemplates fileMessageTemplates `yaml:"message_templates"`
+}
+
+type fileMessageTemplates struct {
+	Announcement string `yaml:"announcement"`
+	Reply        string `yaml:"reply"`
+}
+
+func (t fileMessageTemplates) toMessageTemplates() MessageTemplates {
+	return MessageTemplates{Announcement: t.Announcement, Reply: t.Reply}
+}
+
+type filePrompts struct {
+	Dir        string           `yaml:"dir"`
+	Default    string           `yaml:"default"`
+	Categories []filePromptRule `yaml:"categories"`
+}
+
+type filePromptRule struct {
+	Categories []int  `yaml:"categories"`
+	Template   string `yaml:"template"`
+}
+
+type fileDestination struct {
+	Name              string       `yaml:"name"`
+	ChatID            int64        `yaml:"chat_id"`
+	ThreadID          int          `yaml:"thread_id"`
+	ThreadMode        string       `yaml:"thread_mode"`
+	Routes            []fileRoute  `yaml:"routes"`
+	PremiumNotice     *bool        `yaml:"premium_notice"`
+	PremiumNoticeText string       `yaml:"premium_notice_text"`
+	Photos            *bool        `yaml:"photos"`
+	Buttons           []fileButton `yaml:"buttons"`
+	Categories        []int        `yaml:"categories"`
+	IgnoredCategories []int        `yaml:"ignored_categories"`
+	Tags              []string     `yaml:"tags"`
+	IgnoredTags       []string     `yaml:"ignored_tags"`
+	Prompt            string       `yaml:"prompt"`
+	Locale            string       `yaml:"locale"`
+
+	MessageTemplates fileMessageTemplates `yaml:"message_templates"`
+}
+
+type fileButton struct {
+	Type string `yaml:"type"`
+	Text string `yaml:"text"`
+	URL  string `yaml:"url"`
+	Row  int    `yaml:"row"`
+}
+
+type fileWatch struct {
+	Name       string   `yaml:"name"`
+	Keywords   []string `yaml:"keywords"`
+	Patterns   []string `yaml:"patterns"`
+	Fields     []string `yaml:"fields"`
+	ChatID     int64    `yaml:"chat_id"`
+	ThreadID   int      `yaml:"thread_id"`
+	ThreadMode string   `yaml:"thread_mode"`
+	Locale     string   `yaml:"locale"`
+	Prefix     string   `yaml:"prefix"`
+	Mentions   []string `yaml:"mentions"`
+}
+
+type fileRoute struct {
+	Name          string   `yaml:"name"`
+	Categories    []int    `yaml:"categories"`
+	CategorySlugs []string `yaml:"category_slugs"`
+	Tags          []string `yaml:"tags"`
+	AuthorRoles   []string `yaml:"author_roles"`
+	ChatID        int64    `yaml:"chat_id"`
+	ThreadID      int      `yaml:"thread_id"`
+	ThreadMode    string   `yaml:"thread_mode"`
+}
+
+// loadFile дополняет конфигурацию данными из YAML-файла
+func (cfg *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	var file fileConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	if len(file.Destinations) > 0 && len(file.Routes) > 0 {
+		return fmt.Errorf("invalid %s: top-level routes cannot be combined with destinations, move them into a destination", path)
+	}
+
+	if cfg.Routes, err = toRoutes(file.Routes, cfg.TelegramThreadMode); err != nil {
+		return fmt.Errorf("invalid %s: %v", path, err)
+	}
+
+	cfg.MessageTemplates = file.MessageTemplates.toMessageTemplates().withDefaults(cfg.MessageTemplates)
+
+	if err := cfg.applyPrompts(&file.Prompts); err != nil {
+		return fmt.Errorf("invalid prompts in %s: %v", path, err)
+	}
+
+	names := make(map[string]bool)
+	for i, d := range file.Destinations {
+		destination, err := d.toDestination(cfg)
+		if err != nil {
+			return fmt.Errorf("invalid destination %s in %s: %v", nameOrIndex(d.Name, i), path, err)
+		}
+		if names[destination.Name] {
+			return fmt.Errorf("invalid %s: duplicate destination name %q", path, destination.Name)
+		}
+		names[destination.Name] = true
+		cfg.Destinations = append(cfg.Destinations, destination)
+	}
+
+	watchNames := make(map[string]bool)
+	for i, w := range file.Watches {
+		watch, err := w.toWatchRule(cfg, i)
+		if err != nil {
+			return fmt.Errorf("invalid watch %s in %s: %v", nameOrIndex(w.Name, i), path, err)
+		}
+		if watchNames[watch.Name] {
+			return fmt.Errorf("invalid %s: duplicate watch name %q", path, watch.Name)
+		}
+		watchNames[watch.Name] = true
+		cfg.Watches = append(cfg.Watches, watch)
+	}
+
+	return nil
+}
+
+// applyPrompts переопределяет настройки шаблонов из окружения.
+// Существование шаблонов проверяется при загрузке в пакете ai
+func (cfg *Config) applyPrompts(p *filePrompts) error {
+	if p.Dir != "" {
+		cfg.PromptsDir = p.Dir
+	}
+	if p.Default != "" {
+		cfg.PromptTemplate = p.Default
+	}
+	for i, rule := range p.Categories {
+		if rule.Template == "" || len(rule.Categories) == 0 {
+			return fmt.Errorf("rule #%d: categories and template are required", i+1)
+		}
+		cfg.PromptRules = append(cfg.PromptRules, PromptRule{Categories: rule.Categories, Template: rule.Template})
+	}
+	return nil
+}
+
+func (d *fileDestination) toDestination(cfg *Config) (Destination, error) {
+	if d.Name == "" {
+		return Destination{}, fmt.Errorf("name is required")
+	}
+	if d.ChatID == 0 {
+		return Destination{}, fmt.Errorf("chat_id is required")
+	}
+
+	mode, err := threadModeOrDefault(d.ThreadMode, cfg.TelegramThreadMode)
+	if err != nil {
+		return Destination{}, err
+	}
+
+	routes, err := toRoutes(d.Routes, mode)
+	if err != nil {
+		return Destination{}, err
+	}
+
+	locale := i18n.Normalize(d.Locale)
+	if locale != "" && !i18n.Supported(locale) {
+		return Destination{}, fmt.Errorf("unsupported locale %q (supported: %s)", d.Locale, strings.Join(i18n.Locales(), ", "))
+	}
+
+	premiumNotice := true
+	if d.PremiumNotice != nil {
+		premiumNotice = *d.PremiumNotice
+	}
+	photos := cfg.AnnouncementPhotos
+	if d.Photos != nil {
+		photos = *d.Photos
+	}
+
+	// Без секции buttons используются кнопки из ANNOUNCEMENT_BUTTONS, "buttons: []" отключает их
+	buttons := cfg.AnnouncementButtons
+	if d.Buttons != nil {
+		buttons = make([]Button, 0, len(d.Buttons))
+		for i, b := range d.Buttons {
+			button := Button{Type: b.Type, Text: b.Text, URL: b.URL, Row: b.Row}
+			if err := button.validate(cfg.SubscribeURL); err != nil {
+				return Destination{}, fmt.Errorf("button #%d: %v", i+1, err)
+			}
+			buttons = append(buttons, button)
+		}
+	}
+
+	return Destination{
+		Name:              d.Name,
+		ChatID:            d.ChatID,
+		Thread:            ThreadTarget{ID: d.ThreadID, Mode: mode},
+		Routes:            routes,
+		PremiumNotice:     premiumNotice,
+		PremiumNoticeText: d.PremiumNoticeText,
+		Photos:            photos,
+		Buttons:           buttons,
+		Categories:        d.Categories,
+		IgnoredCategories: d.IgnoredCategories,
+		Tags:              d.Tags,
+		IgnoredTags:       d.IgnoredTags,
+		Prompt:            d.Prompt,
+		Locale:            locale,
+		MessageTemplates:  d.MessageTemplates.toMessageTemplates(),
+	}, nil
+}
+
+func (w *fileWatch) toWatchRule(cfg *Config, index int) (WatchRule, error) {
+	if len(w.Keywords) == 0 && len(w.Patterns) == 0 {
+		return WatchRule{}, fmt.Errorf("keywords or patterns are required")
+	}
+	for _, field := range w.Fields {
+		if !containsFold(watchFields, field) {
+			return WatchRule{}, fmt.Errorf("unknown field %q (expected one of %v)", field, watchFields)
+		}
+	}
+
+	rule := WatchRule{
+		Name:     w.Name,
+		Keywords: w.Keywords,
+		Fields:   w.Fields,
+		Prefix:   w.Prefix,
+	}
+	if rule.Name == "" {
+		rule.Name = fmt.Sprintf("watch_%d", index+1)
+	}
+
+	for _, pattern := range w.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return WatchRule{}, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		rule.Patterns = append(rule.Patterns, re)
+	}
+
+	for _, value := range w.Mentions {
+		mention, err := parseMention(value)
+		if err != nil {
+			return WatchRule{}, err
+		}
+		rule.Mentions = append(rule.Mentions, mention)
+	}
+
+	locale := i18n.Normalize(w.Locale)
+	if locale != "" && !i18n.Supported(locale) {
+		return WatchRule{}, fmt.Errorf("unsupported locale %q (supported: %s)", w.Locale, strings.Join(i18n.Locales(), ", "))
+	}
+
+	// Без chat_id и thread_id правило только выделяет анонсы в обычных чатах
+	if w.ChatID == 0 && w.ThreadID == 0 {
+		if w.ThreadMode != "" || locale != "" {
+			return WatchRule{}, fmt.Errorf("thread_mode and locale require chat_id or thread_id")
+		}
+		if rule.Prefix == "" && len(rule.Mentions) == 0 {
+			return WatchRule{}, fmt.Errorf("chat_id, thread_id, prefix or mentions are required")
+		}
+		return rule, nil
+	}
+
+	mode, err := threadModeOrDefault(w.ThreadMode, cfg.TelegramThreadMode)
+	if err != nil {
+		return WatchRule{}, err
+	}
+	chatID := w.ChatID
+	if chatID == 0 {
+		chatID = cfg.TelegramChatID
+	}
+
+	rule.Destination = &Destination{
+		Name:          "watch:" + rule.Name,
+		ChatID:        chatID,
+		Thread:        ThreadTarget{ID: w.ThreadID, Mode: mode},
+		PremiumNotice: true,
+		Photos:        cfg.AnnouncementPhotos,
+		Buttons:       cfg.AnnouncementButtons,
+		Locale:        locale,
+	}
+	return rule, nil
+}
+
+func toRoutes(fileRoutes []fileRoute, defaultMode string) ([]Route, error) {
+	var routes []Route
+	for i, r := range fileRoutes {
+		route, err := r.toRoute(defaultMode)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %v", nameOrIndex(r.Name, i), err)
+		}
+		if route.Name == "" {
+			route.Name = fmt.Sprintf("route_%d", i+1)
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+func nameOrIndex(name string, index int) string {
+	if name != "" {
+		return name
+	}
+	return fmt.Sprintf("#%d", index+1)
+}
+
+func threadModeOrDefault(mode, defaultMode string) (string, error) {
+	switch mode {
+	case "":
+		return defaultMode, nil
+	case ThreadModeTopic, ThreadModeReply:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("thread_mode must be %s or %s", ThreadModeTopic, ThreadModeReply)
+	}
+}
+
+func (r *fileRoute) toRoute(defaultMode string) (Route, error) {
+	mode, err := threadModeOrDefault(r.ThreadMode, defaultMode)
+	if err != nil {
+		return Route{}, err
+	}
+
+	for _, role := range r.AuthorRoles {
+		if !containsFold(authorRoles, role) {
+			return Route{}, fmt.Errorf("unknown author role %q (expected one of %v)", role, authorRoles)
+		}
+	}
+
+	if r.ChatID == 0 && r.ThreadID == 0 {
+		return Route{}, fmt.Errorf("chat_id or thread_id is required")
+	}
+
+	return Route{
+		Name:          r.Name,
+		Categories:    r.Categories,
+		CategorySlugs: r.CategorySlugs,
+		Tags:          r.Tags,
+		AuthorRoles:   r.AuthorRoles,
+		ChatID:        r.ChatID,
+		Thread:        ThreadTarget{ID: r.ThreadID, Mode: mode},
+	}, nil
+}
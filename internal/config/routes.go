@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"webhook_tg_bot/internal/models"
+)
+
+// Роли авторов, по которым можно маршрутизировать анонсы
+var authorRoles = []string{"admin", "moderator", "staff", "leader", "user"}
+
+// Route правило маршрутизации анонса в чат и thread.
+// Пустой критерий не ограничивает выбор; внутри списка достаточно одного совпадения
+type Route struct {
+	Name          string
+	Categories    []int
+	CategorySlugs []string
+	Tags          []string
+	AuthorRoles   []string
+	ChatID        int64 // 0 - основной чат TELEGRAM_CHAT_ID
+	Thread        ThreadTarget
+}
+
+// Matches проверяет, подходит ли тема под правило
+func (r *Route) Matches(processed *models.ProcessedWebhook) bool {
+	if len(r.Categories) > 0 && !containsInt(r.Categories, processed.CategoryID) {
+		return false
+	}
+	if len(r.CategorySlugs) > 0 && !containsFold(r.CategorySlugs, processed.CategorySlug) {
+		return false
+	}
+	if len(r.Tags) > 0 && !containsAnyFold(r.Tags, processed.Tags) {
+		return false
+	}
+	if len(r.AuthorRoles) > 0 && !containsFold(r.AuthorRoles, processed.AuthorRole) {
+		return false
+	}
+	return true
+}
+
+var threadCategoriesKey = regexp.MustCompile(`^THREAD_CATEGORIES_(\d+)$`)
+
+// loadEnvRoutes читает пары TELEGRAM_THREAD_ID_N / THREAD_CATEGORIES_N (и THREAD_MODE_N)
+func loadEnvRoutes(defaultMode string) ([]Route, error) {
+	var numbers []int
+	for _, env := range os.Environ() {
+		key, _, _ := strings.Cut(env, "=")
+		if match := threadCategoriesKey.FindStringSubmatch(key); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			numbers = append(numbers, n)
+		}
+	}
+	sort.Ints(numbers)
+
+	var routes []Route
+	for _, i := range numbers {
+		threadIDKey := fmt.Sprintf("TELEGRAM_THREAD_ID_%d", i)
+		categoriesKey := fmt.Sprintf("THREAD_CATEGORIES_%d", i)
+
+		threadIDStr := os.Getenv(threadIDKey)
+		categoriesStr := os.Getenv(categoriesKey)
+		if threadIDStr == "" || categoriesStr == "" {
+			continue
+		}
+
+		threadID, err := strconv.Atoi(threadIDStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", threadIDKey, err)
+		}
+		threadMode, err := parseThreadMode(fmt.Sprintf("THREAD_MODE_%d", i), defaultMode)
+		if err != nil {
+			return nil, err
+		}
+
+		route := Route{
+			Name:   fmt.Sprintf("thread_%d", i),
+			Thread: ThreadTarget{ID: threadID, Mode: threadMode},
+		}
+		for _, catStr := range strings.Split(categoriesStr, ",") {
+			catStr = strings.TrimSpace(catStr)
+			if catStr != "" {
+				categoryID, err := strconv.Atoi(catStr)
+				if err != nil {
+					return nil, fmt.Errorf("invalid category ID '%s' in %s: %v", catStr, categoriesKey, err)
+				}
+				route.Categories = append(route.Categories, categoryID)
+			}
+		}
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
+
+func containsInt(list []int, value int) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyFold(list []string, values []string) bool {
+	for _, value := range values {
+		if containsFold(list, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+	"webhook_tg_bot/internal/models"
+)
+
+func TestRouteMatches(t *testing.T) {
+	topic := &models.ProcessedWebhook{
+		CategoryID:   5,
+		CategorySlug: "dev-ops",
+		Tags:         []string{"kubernetes", "help"},
+		AuthorRole:   "moderator",
+	}
+
+	tests := []struct {
+		name  string
+		route Route
+		want  bool
+	}{
+		{"empty route matches everything", Route{}, true},
+		{"category", Route{Categories: []int{1, 5}}, true},
+		{"other category", Route{Categories: []int{1, 2}}, false},
+		{"category slug ignores case", Route{CategorySlugs: []string{"Dev-Ops"}}, true},
+		{"other category slug", Route{CategorySlugs: []string{"general"}}, false},
+		{"any tag is enough", Route{Tags: []string{"docker", "KUBERNETES"}}, true},
+		{"no common tag", Route{Tags: []string{"docker"}}, false},
+		{"author role", Route{AuthorRoles: []string{"admin", "moderator"}}, true},
+		{"other author role", Route{AuthorRoles: []string{"admin"}}, false},
+		{"all conditions match", Route{Categories: []int{5}, Tags: []string{"help"}, AuthorRoles: []string{"moderator"}}, true},
+		{"one condition fails", Route{Categories: []int{5}, Tags: []string{"docker"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.route.Matches(topic); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
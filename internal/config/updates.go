@@ -0,0 +1,86 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Способы получения обновлений Telegram (команды администраторов)
+const (
+	BotUpdatesOff     = "off"     // бот только отправляет сообщения
+	BotUpdatesPolling = "polling" // long polling через getUpdates
+	BotUpdatesWebhook = "webhook" // Telegram присылает обновления на сервер вебхуков
+)
+
+// loadBotUpdates читает BOT_UPDATES, BOT_WEBHOOK_URL, ADMIN_USER_IDS и BOT_CHAT_ADMINS
+func (cfg *Config) loadBotUpdates() error {
+	cfg.BotUpdates = strings.ToLower(os.Getenv("BOT_UPDATES"))
+	if cfg.BotUpdates == "" {
+		cfg.BotUpdates = BotUpdatesOff
+	}
+
+	switch cfg.BotUpdates {
+	case BotUpdatesOff, BotUpdatesPolling:
+	case BotUpdatesWebhook:
+		cfg.BotWebhookURL = os.Getenv("BOT_WEBHOOK_URL")
+		webhookURL, err := url.Parse(cfg.BotWebhookURL)
+		if err != nil || webhookURL.Scheme != "https" || webhookURL.Host == "" {
+			return fmt.Errorf("BOT_WEBHOOK_URL must be a public https URL when BOT_UPDATES=webhook")
+		}
+		// Путь из публичного адреса регистрируется на сервере вебхуков (за прокси он должен совпадать)
+		cfg.BotWebhookPath = webhookURL.Path
+		if cfg.BotWebhookPath == "" || cfg.BotWebhookPath == "/" {
+			return fmt.Errorf("BOT_WEBHOOK_URL must contain a path, e.g. https://bot.example.com/telegram")
+		}
+		if cfg.BotWebhookPath == cfg.WebhookPath {
+			return fmt.Errorf("BOT_WEBHOOK_URL path must differ from WEBHOOK_PATH")
+		}
+		// secret_token допускает только [A-Za-z0-9_-], поэтому выводится из WEBHOOK_SECRET
+		sum := sha256.Sum256([]byte("telegram:" + cfg.WebhookSecret))
+		cfg.BotWebhookSecret = hex.EncodeToString(sum[:])
+	default:
+		return fmt.Errorf("invalid BOT_UPDATES: %s (expected off, polling or webhook)", cfg.BotUpdates)
+	}
+
+	for _, item := range strings.Split(os.Getenv("ADMIN_USER_IDS"), ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(item, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid ADMIN_USER_IDS: %v", err)
+		}
+		cfg.AdminUserIDs = append(cfg.AdminUserIDs, id)
+	}
+
+	// Администраторы чатов из конфигурации управляют ботом в своем чате, только если это включено явно
+	cfg.ChatAdmins = strings.EqualFold(os.Getenv("BOT_CHAT_ADMINS"), "true")
+	return nil
+}
+
+// IsAdminUser проверяет, есть ли пользователь Telegram в ADMIN_USER_IDS
+func (cfg *Config) IsAdminUser(userID int64) bool {
+	for _, id := range cfg.AdminUserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// DestinationsInChat возвращает чаты из конфигурации с указанным chat_id
+func (cfg *Config) DestinationsInChat(chatID int64) []*Destination {
+	var result []*Destination
+	for i := range cfg.Destinations {
+		if cfg.Destinations[i].ChatID == chatID {
+			result = append(result, &cfg.Destinations[i])
+		}
+	}
+	return result
+}
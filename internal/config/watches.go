@@ -0,0 +1,133 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"webhook_tg_bot/internal/models"
+)
+
+// Поля темы, в которых правило наблюдения ищет совпадения
+const (
+	WatchFieldTitle   = "title"   // заголовок темы
+	WatchFieldContent = "content" // текст первого поста (raw)
+	WatchFieldTags    = "tags"    // каждый тег по отдельности
+)
+
+var watchFields = []string{WatchFieldTitle, WatchFieldContent, WatchFieldTags}
+
+// WatchRule правило наблюдения: ищет ключевые слова и регулярные выражения в теме
+// независимо от категории. Совпавшая тема отправляется в чат правила (Destination),
+// а анонс выделяется префиксом и упоминаниями
+type WatchRule struct {
+	Name     string
+	Keywords []string         // подстроки без учета регистра
+	Patterns []*regexp.Regexp // регулярные выражения; регистр учитывается, если не задан (?i)
+	Fields   []string         // где искать; пусто - во всех полях
+	Prefix   string           // строка перед анонсом, может содержать разметку
+	Mentions []Mention
+
+	// Чат и thread для совпавших тем; nil - правило только выделяет обычные анонсы
+	Destination *Destination
+}
+
+// Mention упоминание пользователя Telegram в анонсе
+type Mention struct {
+	Username string // без @
+	UserID   int64  // для пользователей без username
+	Name     string // текст ссылки для UserID
+}
+
+// Matches проверяет, есть ли в теме ключевое слово или совпадение с регулярным выражением
+func (w *WatchRule) Matches(processed *models.ProcessedWebhook) bool {
+	for _, text := range w.texts(processed) {
+		lower := strings.ToLower(text)
+		for _, keyword := range w.Keywords {
+			if strings.Contains(lower, strings.ToLower(keyword)) {
+				return true
+			}
+		}
+		for _, pattern := range w.Patterns {
+			if pattern.MatchString(text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// texts возвращает тексты темы из полей правила
+func (w *WatchRule) texts(processed *models.ProcessedWebhook) []string {
+	var texts []string
+	if w.searches(WatchFieldTitle) {
+		texts = append(texts, processed.TopicTitle)
+	}
+	if w.searches(WatchFieldContent) {
+		texts = append(texts, processed.Content)
+	}
+	if w.searches(WatchFieldTags) {
+		texts = append(texts, processed.Tags...)
+	}
+	return texts
+}
+
+func (w *WatchRule) searches(field string) bool {
+	return len(w.Fields) == 0 || containsFold(w.Fields, field)
+}
+
+// Highlights сообщает, выделяет ли правило анонс, отправленный в chatID/threadID:
+// правило без своего чата выделяет все анонсы, остальные - только анонсы в своем чате и thread'е
+func (w *WatchRule) Highlights(chatID int64, threadID int) bool {
+	return w.Destination == nil || (w.Destination.ChatID == chatID && w.Destination.Thread.ID == threadID)
+}
+
+// MatchingWatches возвращает правила наблюдения, под которые подходит тема
+func (cfg *Config) MatchingWatches(processed *models.ProcessedWebhook) []*WatchRule {
+	var result []*WatchRule
+	for i := range cfg.Watches {
+		if cfg.Watches[i].Matches(processed) {
+			result = append(result, &cfg.Watches[i])
+		}
+	}
+	return result
+}
+
+// HasWatchDestinations сообщает, есть ли правила наблюдения со своим чатом.
+// Только они отправляют темы из неотслеживаемых категорий
+func (cfg *Config) HasWatchDestinations() bool {
+	for i := range cfg.Watches {
+		if cfg.Watches[i].Destination != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// RoutedByWatch проверяет, отправляет ли тему в свой чат хотя бы одно правило наблюдения
+func (cfg *Config) RoutedByWatch(processed *models.ProcessedWebhook) bool {
+	for _, watch := range cfg.MatchingWatches(processed) {
+		if watch.Destination != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMention разбирает "@username", "123456789" или "123456789:Имя"
+func parseMention(value string) (Mention, error) {
+	value = strings.TrimSpace(value)
+	if username, found := strings.CutPrefix(value, "@"); found {
+		if username == "" {
+			return Mention{}, fmt.Errorf("empty username in mention %q", value)
+		}
+		return Mention{Username: username}, nil
+	}
+
+	idStr, name, _ := strings.Cut(value, ":")
+	userID, err := strconv.ParseInt(strings.TrimSpace(idStr), 10, 64)
+	if err != nil {
+		return Mention{}, fmt.Errorf("invalid mention %q: expected @username or Telegram user ID", value)
+	}
+	return Mention{UserID: userID, Name: strings.TrimSpace(name)}, nil
+}
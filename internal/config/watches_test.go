@@ -0,0 +1,122 @@
+package config
+
+import (
+	"regexp"
+	"testing"
+	"webhook_tg_bot/internal/models"
+)
+
+func TestWatchRuleMatches(t *testing.T) {
+	topic := &models.ProcessedWebhook{
+		TopicTitle: "Kubernetes cluster keeps restarting",
+		Content:    "Pods crash with OOMKilled after the upgrade to 1.29",
+		Tags:       []string{"devops", "help-wanted"},
+	}
+
+	tests := []struct {
+		name string
+		rule WatchRule
+		want bool
+	}{
+		{"keyword in title", WatchRule{Keywords: []string{"cluster"}}, true},
+		{"keyword ignores case", WatchRule{Keywords: []string{"KUBERNETES"}}, true},
+		{"keyword in content", WatchRule{Keywords: []string{"oomkilled"}}, true},
+		{"keyword in tag", WatchRule{Keywords: []string{"help-wanted"}}, true},
+		{"no keyword matches", WatchRule{Keywords: []string{"postgres"}}, false},
+		{"any keyword is enough", WatchRule{Keywords: []string{"postgres", "pods"}}, true},
+		{"pattern matches", WatchRule{Patterns: []*regexp.Regexp{regexp.MustCompile(`\d+\.\d+`)}}, true},
+		{"pattern is case sensitive", WatchRule{Patterns: []*regexp.Regexp{regexp.MustCompile(`kubernetes`)}}, false},
+		{"pattern with (?i)", WatchRule{Patterns: []*regexp.Regexp{regexp.MustCompile(`(?i)kubernetes`)}}, true},
+		{"title only skips content", WatchRule{Keywords: []string{"pods"}, Fields: []string{WatchFieldTitle}}, false},
+		{"content only", WatchRule{Keywords: []string{"pods"}, Fields: []string{WatchFieldContent}}, true},
+		{"tags only skip title", WatchRule{Keywords: []string{"cluster"}, Fields: []string{WatchFieldTags}}, false},
+		{"tags only", WatchRule{Keywords: []string{"devops"}, Fields: []string{WatchFieldTags}}, true},
+		{"empty rule", WatchRule{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Matches(topic); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchRuleHighlights(t *testing.T) {
+	routed := WatchRule{Destination: &Destination{ChatID: -100, Thread: ThreadTarget{ID: 7}}}
+
+	tests := []struct {
+		name     string
+		rule     WatchRule
+		chatID   int64
+		threadID int
+		want     bool
+	}{
+		{"rule without chat highlights everywhere", WatchRule{}, -200, 0, true},
+		{"own chat and thread", routed, -100, 7, true},
+		{"own chat, other thread", routed, -100, 0, false},
+		{"other chat", routed, -200, 7, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Highlights(tt.chatID, tt.threadID); got != tt.want {
+				t.Errorf("Highlights(%d, %d) = %v, want %v", tt.chatID, tt.threadID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoutedByWatch(t *testing.T) {
+	cfg := &Config{Watches: []WatchRule{
+		{Name: "highlight", Keywords: []string{"kubernetes"}},
+		{Name: "routed", Keywords: []string{"postgres"}, Destination: &Destination{ChatID: -100}},
+	}}
+
+	tests := []struct {
+		title string
+		want  bool
+	}{
+		{"Kubernetes upgrade", false},
+		{"Postgres replication", true},
+		{"Something else", false},
+	}
+
+	if !cfg.HasWatchDestinations() {
+		t.Fatal("HasWatchDestinations() = false, want true")
+	}
+	for _, tt := range tests {
+		if got := cfg.RoutedByWatch(&models.ProcessedWebhook{TopicTitle: tt.title}); got != tt.want {
+			t.Errorf("RoutedByWatch(%q) = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestParseMention(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Mention
+		wantErr bool
+	}{
+		{"@alice", Mention{Username: "alice"}, false},
+		{" @bob ", Mention{Username: "bob"}, false},
+		{"123456789", Mention{UserID: 123456789}, false},
+		{"123456789:Alice Smith", Mention{UserID: 123456789, Name: "Alice Smith"}, false},
+		{"123456789 : Alice ", Mention{UserID: 123456789, Name: "Alice"}, false},
+		{"@", Mention{}, true},
+		{"alice", Mention{}, true},
+		{"", Mention{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseMention(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseMention(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseMention(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package discourse
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/models"
+)
+
+// Client минимальный клиент Discourse API
+type Client struct {
+	baseURL     string
+	apiKey      string
+	apiUsername string
+	http        *http.Client
+}
+
+// NewClient создает клиент Discourse API
+func NewClient(cfg *config.Config) *Client {
+	return &Client{
+		baseURL:     strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey:      cfg.DiscourseAPIKey,
+		apiUsername: cfg.DiscourseAPIUsername,
+		http:        &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Enabled сообщает, настроен ли доступ к API
+func (c *Client) Enabled() bool {
+	return c.apiKey != ""
+}
+
+// GetFirstPost загружает первый пост темы
+func (c *Client) GetFirstPost(topicID int) (*models.Post, error) {
+	var post models.Post
+	if err := c.get(fmt.Sprintf("/posts/by_number/%d/1.json", topicID), &post); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// GetCategories загружает публичные категории форума вместе с подкатегориями.
+// Запрос выполняется без API-ключа, чтобы закрытые и служебные категории не попали в список
+func (c *Client) GetCategories() ([]models.Category, error) {
+	var response struct {
+		CategoryList struct {
+			Categories []struct {
+				models.Category
+				Subcategories []models.Category `json:"subcategory_list"`
+			} `json:"categories"`
+		} `json:"category_list"`
+	}
+	if err := c.request("/categories.json?include_subcategories=true", &response, false); err != nil {
+		return nil, err
+	}
+
+	var categories []models.Category
+	for _, category := range response.CategoryList.Categories {
+		categories = append(categories, category.Category)
+		categories = append(categories, category.Subcategories...)
+	}
+	return categories, nil
+}
+
+func (c *Client) get(path string, out interface{}) error {
+	return c.request(path, out, true)
+}
+
+// request выполняет GET-запрос; authenticated - передать API-ключ, если он задан
+func (c *Client) request(path string, out interface{}, authenticated bool) error {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	if authenticated && c.apiKey != "" {
+		req.Header.Set("Api-Key", c.apiKey)
+		req.Header.Set("Api-Username", c.apiUsername)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("discourse API request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("discourse API returned %s for %s", resp.Status, path)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode discourse API response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package i18n
+
+var en = Catalog{
+	"language.name": "English",
+
+	"announcement.created": "published a new post:",
+	"announcement.link":    "Open topic",
+	"announcement.tags":    "Tags:",
+	"announcement.no_tags": "none",
+	"announcement.removed": "🗑 The topic «%s» is no longer available on the forum.",
+
+	"premium.notice": "💎 <b>This section is available by subscription only.</b>\n" +
+		"You can get VIP access in the Telegram bot: @gig_combot",
+
+	"category.numbered": "Category %d",
+	"category.unknown":  "Main section",
+
+	"reply.answered": "replied in",
+	"reply.link":     "Open reply",
+	"reply.accepted": "✅ <b>Marked as the solution</b>",
+
+	"button.topic":     "📖 Open topic",
+	"button.category":  "📂 Category",
+	"button.reply":     "💬 Reply",
+	"button.subscribe": "💎 Subscribe",
+
+	"command.forbidden":         "⛔ This command is available to administrators only.",
+	"command.unconfigured_chat": "⛔ This chat is not in the bot configuration. Manage all chats from a private message with the bot.",
+	"command.unknown":           "Unknown command. See /help for the list of commands.",
+	"command.failed":            "❌ Error: %s",
+	"command.help": "<b>Admin commands</b>\n" +
+		"/status - bot status\n" +
+		"/mute &lt;category&gt; [duration] - mute announcements of a category (ID or slug, duration like 2h)\n" +
+		"/unmute [category] - unmute announcements\n" +
+		"/routes - chats and routing rules\n" +
+		"/test - send a test announcement\n" +
+		"/resend &lt;topic ID&gt; - announce a topic again",
+
+	"status.title":         "📊 <b>Bot status</b>",
+	"status.uptime":        "Uptime: %s",
+	"status.ai":            "AI: %s",
+	"status.storage":       "Storage: %s",
+	"status.destinations":  "Chats: %d",
+	"status.queue":         "Queued events: %d",
+	"status.announcements": "Announcements sent: %d",
+	"status.dead_letters":  "Undelivered messages: %d",
+	"status.mutes":         "Muted categories:",
+	"status.no_mutes":      "No muted categories",
+
+	"mute.usage":            "Usage: /mute &lt;category ID or slug&gt; [duration, e.g. 2h]",
+	"mute.invalid_duration": "Invalid duration %s: use e.g. 30m, 2h or 72h",
+	"mute.done":             "🔇 Announcements of category %s are muted %s",
+	"mute.scope_all":        "in all chats",
+	"mute.scope_chat":       "in this chat",
+	"mute.until":            "until %s",
+	"unmute.done":           "🔔 Unmuted: %d",
+
+	"routes.title":   "🗺 <b>Routing</b>",
+	"routes.watches": "👁 <b>Watch rules</b>",
+
+	"test.title":   "Delivery check",
+	"test.content": "A test announcement sent by the /test command.",
+	"test.sent":    "✅ %s: test announcement sent",
+	"test.failed":  "❌ %s: %s",
+
+	"resend.usage": "Usage: /resend &lt;topic ID&gt;",
+	"resend.done":  "✅ Topic %d has been announced again",
+
+	"subscription.welcome": "👋 Hi! I can send you new forum topics in direct messages: choose categories, tags, authors or keywords.",
+	"subscription.help": "<b>Subscriptions</b>\n" +
+		"/subscriptions - your subscriptions and settings menu\n" +
+		"/subscribe &lt;category|tag|author|keyword&gt; &lt;value&gt; - subscribe\n" +
+		"/unsubscribe &lt;category|tag|author|keyword&gt; &lt;value&gt; - unsubscribe\n" +
+		"/unsubscribe - unsubscribe from everything",
+	"subscription.usage":                  "Usage: /subscribe &lt;category|tag|author|keyword&gt; &lt;value&gt;, e.g. /subscribe tag docker",
+	"subscription.added":                  "✅ Subscribed: %s - %s",
+	"subscription.exists":                 "You are already subscribed to %s",
+	"subscription.limit":                  "❌ Up to %d entries of each kind are allowed",
+	"subscription.removed":                "🔕 Unsubscribed from %s",
+	"subscription.not_found":              "You are not subscribed to %s",
+	"subscription.stopped":                "🔕 You have unsubscribed from everything",
+	"subscription.title":                  "🔔 <b>Your subscriptions</b>",
+	"subscription.none":                   "—",
+	"subscription.hint":                   "A topic is sent if it matches any entry. Add a tag, author or keyword with a command, e.g. /subscribe keyword kubernetes",
+	"subscription.categories":             "📂 Tap a category to subscribe or unsubscribe (page %d of %d)",
+	"subscription.categories_unavailable": "Could not load forum categories. Use /subscribe category &lt;ID or slug&gt;",
+	"subscription.list":                   "%s: tap an entry to remove it. Add: /subscribe %s &lt;value&gt;",
+	"subscription.stop":                   "🔕 Unsubscribe from everything",
+	"subscription.back":                   "↩️ Back",
+
+	"subscription.kind.category": "📂 Categories",
+	"subscription.kind.tag":      "🏷 Tags",
+	"subscription.kind.author":   "👤 Authors",
+	"subscription.kind.keyword":  "🔎 Keywords",
+
+	"summary.question": "The author asks: %s",
+}
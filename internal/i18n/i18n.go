@@ -0,0 +1,60 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale язык, на который откатываются отсутствующие переводы
+const DefaultLocale = "ru"
+
+// Catalog переводы сообщений одного языка: ключ -> шаблон fmt
+type Catalog map[string]string
+
+var catalogs = map[string]Catalog{
+	"ru": ru,
+	"en": en,
+}
+
+// Supported проверяет, есть ли каталог для языка
+func Supported(locale string) bool {
+	_, exists := catalogs[locale]
+	return exists
+}
+
+// Locales возвращает поддерживаемые языки
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Normalize приводит код языка к виду каталога ("EN", "en-US" -> "en")
+func Normalize(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if base, _, found := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-"); found {
+		return base
+	}
+	return locale
+}
+
+// T возвращает перевод сообщения key с подстановкой args.
+// Если перевода нет, используется DefaultLocale, а затем сам ключ
+func T(locale, key string, args ...any) string {
+	message, exists := catalogs[locale][key]
+	if !exists {
+		message, exists = catalogs[DefaultLocale][key]
+	}
+	if !exists {
+		message = key
+	}
+
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
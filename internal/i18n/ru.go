@@ -0,0 +1,97 @@
+package i18n
+
+var ru = Catalog{
+	"language.name": "русский",
+
+	"announcement.created": "создал новый пост:",
+	"announcement.link":    "Ссылка на тему",
+	"announcement.tags":    "Теги:",
+	"announcement.no_tags": "нет",
+	"announcement.removed": "🗑 Тема «%s» больше недоступна на форуме.",
+
+	"premium.notice": "💎 <b>Данный раздел доступен только по подписке.</b>\n" +
+		"Оформить VIP можно в тг-боте: @gig_combot",
+
+	"category.numbered": "Раздел %d",
+	"category.unknown":  "Основной раздел",
+
+	"reply.answered": "ответил в теме",
+	"reply.link":     "Перейти к ответу",
+	"reply.accepted": "✅ <b>Ответ отмечен как решение</b>",
+
+	"button.topic":     "📖 Открыть тему",
+	"button.category":  "📂 Категория",
+	"button.reply":     "💬 Ответить",
+	"button.subscribe": "💎 Оформить подписку",
+
+	"command.forbidden":         "⛔ Команда доступна только администраторам.",
+	"command.unconfigured_chat": "⛔ Этого чата нет в конфигурации бота. Управлять всеми чатами можно в личных сообщениях с ботом.",
+	"command.unknown":           "Неизвестная команда. Список команд: /help",
+	"command.failed":            "❌ Ошибка: %s",
+	"command.help": "<b>Команды администратора</b>\n" +
+		"/status - состояние бота\n" +
+		"/mute &lt;категория&gt; [срок] - отключить анонсы категории (ID или slug, срок вида 2h)\n" +
+		"/unmute [категория] - снова включить анонсы\n" +
+		"/routes - чаты и правила маршрутизации\n" +
+		"/test - отправить тестовый анонс\n" +
+		"/resend &lt;ID темы&gt; - отправить анонс темы повторно",
+
+	"status.title":         "📊 <b>Состояние бота</b>",
+	"status.uptime":        "Работает: %s",
+	"status.ai":            "AI: %s",
+	"status.storage":       "Хранилище: %s",
+	"status.destinations":  "Чатов: %d",
+	"status.queue":         "В очереди: %d",
+	"status.announcements": "Отправлено анонсов: %d",
+	"status.dead_letters":  "Недоставленных сообщений: %d",
+	"status.mutes":         "Отключенные категории:",
+	"status.no_mutes":      "Отключенных категорий нет",
+
+	"mute.usage":            "Использование: /mute &lt;ID или slug категории&gt; [срок, например 2h]",
+	"mute.invalid_duration": "Некорректный срок %s: укажите, например, 30m, 2h или 72h",
+	"mute.done":             "🔇 Анонсы категории %s отключены %s",
+	"mute.scope_all":        "во всех чатах",
+	"mute.scope_chat":       "в этом чате",
+	"mute.until":            "до %s",
+	"unmute.done":           "🔔 Снято отключений: %d",
+
+	"routes.title":   "🗺 <b>Маршрутизация</b>",
+	"routes.watches": "👁 <b>Правила наблюдения</b>",
+
+	"test.title":   "Проверка доставки",
+	"test.content": "Тестовый анонс, отправленный командой /test.",
+	"test.sent":    "✅ %s: тестовый анонс отправлен",
+	"test.failed":  "❌ %s: %s",
+
+	"resend.usage": "Использование: /resend &lt;ID темы&gt;",
+	"resend.done":  "✅ Анонс темы %d отправлен повторно",
+
+	"subscription.welcome": "👋 Привет! Я могу присылать новые темы форума в личные сообщения: выберите категории, теги, авторов или ключевые слова.",
+	"subscription.help": "<b>Подписки</b>\n" +
+		"/subscriptions - ваши подписки и меню настройки\n" +
+		"/subscribe &lt;category|tag|author|keyword&gt; &lt;значение&gt; - подписаться\n" +
+		"/unsubscribe &lt;category|tag|author|keyword&gt; &lt;значение&gt; - отписаться\n" +
+		"/unsubscribe - отписаться от всего",
+	"subscription.usage":                  "Использование: /subscribe &lt;category|tag|author|keyword&gt; &lt;значение&gt;, например /subscribe tag docker",
+	"subscription.added":                  "✅ Подписка добавлена: %s - %s",
+	"subscription.exists":                 "Вы уже подписаны на %s",
+	"subscription.limit":                  "❌ Можно добавить не больше %d значений каждого вида",
+	"subscription.removed":                "🔕 Подписка на %s удалена",
+	"subscription.not_found":              "Вы не подписаны на %s",
+	"subscription.stopped":                "🔕 Вы отписались от всех тем",
+	"subscription.title":                  "🔔 <b>Ваши подписки</b>",
+	"subscription.none":                   "—",
+	"subscription.hint":                   "Тема приходит, если совпало хотя бы одно условие. Тег, автора или ключевое слово можно добавить командой, например /subscribe keyword kubernetes",
+	"subscription.categories":             "📂 Нажмите на категорию, чтобы подписаться или отписаться (страница %d из %d)",
+	"subscription.categories_unavailable": "Не удалось загрузить категории форума. Используйте /subscribe category &lt;ID или slug&gt;",
+	"subscription.list":                   "%s: нажмите на значение, чтобы удалить его. Добавить: /subscribe %s &lt;значение&gt;",
+	"subscription.stop":                   "🔕 Отписаться от всего",
+	"subscription.back":                   "↩️ Назад",
+
+	"subscription.kind.category": "📂 Категории",
+	"subscription.kind.tag":      "🏷 Теги",
+	"subscription.kind.author":   "👤 Авторы",
+	"subscription.kind.keyword":  "🔎 Ключевые слова",
+
+	"summary.question": "Автор задает вопрос: %s",
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+var (
+	counters = make(map[string]int64)
+	mutex    sync.RWMutex
+)
+
+// Inc увеличивает счетчик на единицу
+func Inc(name string) {
+	Add(name, 1)
+}
+
+// Add увеличивает счетчик на delta
+func Add(name string, delta int64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	counters[name] += delta
+}
+
+// Set устанавливает текущее значение (для величин вроде длины очереди)
+func Set(name string, value int64) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	counters[name] = value
+}
+
+// Snapshot возвращает копию текущих значений счетчиков
+func Snapshot() map[string]int64 {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	result := make(map[string]int64, len(counters))
+	for name, value := range counters {
+		result[name] = value
+	}
+	return result
+}
+
+// Handler отдает счетчики в формате JSON
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Snapshot())
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"strconv"
+	"strings"
+	"time"
+)
 
 // WebhookTopic представляет данные о созданной теме
 type WebhookTopic struct {
@@ -14,21 +18,29 @@ type WebhookPost struct {
 
 // Topic структура темы из вебхука
 type Topic struct {
-	ID         int       `json:"id"`
-	Title      string    `json:"title"`
-	FancyTitle string    `json:"fancy_title"`
-	PostsCount int       `json:"posts_count"`
-	CreatedAt  time.Time `json:"created_at"`
-	Views      int       `json:"views"`
-	ReplyCount int       `json:"reply_count"`
-	LikeCount  int       `json:"like_count"`
-	CategoryID int       `json:"category_id"`
-	WordCount  int       `json:"word_count"`
-	UserID     int       `json:"user_id"`
-	Tags       []string  `json:"tags"`
-	Slug       string    `json:"slug"`
-	CreatedBy  User      `json:"created_by"`
-	LastPoster User      `json:"last_poster"`
+	ID         int        `json:"id"`
+	Title      string     `json:"title"`
+	FancyTitle string     `json:"fancy_title"`
+	PostsCount int        `json:"posts_count"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Views      int        `json:"views"`
+	ReplyCount int        `json:"reply_count"`
+	LikeCount  int        `json:"like_count"`
+	CategoryID int        `json:"category_id"`
+	WordCount  int        `json:"word_count"`
+	UserID     int        `json:"user_id"`
+	Tags       []string   `json:"tags"`
+	Slug       string     `json:"slug"`
+	Excerpt    string     `json:"excerpt"`
+	Visible    *bool      `json:"visible"` // nil, если поле не пришло в вебхуке
+	DeletedAt  *time.Time `json:"deleted_at"`
+	CreatedBy  User       `json:"created_by"`
+	LastPoster User       `json:"last_poster"`
+}
+
+// IsHidden сообщает, что тема удалена или скрыта из списков
+func (t *Topic) IsHidden() bool {
+	return t.DeletedAt != nil || (t.Visible != nil && !*t.Visible)
 }
 
 // Post структура поста из вебхука
@@ -52,6 +64,7 @@ type Post struct {
 	Admin           bool      `json:"admin"`
 	Moderator       bool      `json:"moderator"`
 	Staff           bool      `json:"staff"`
+	AcceptedAnswer  bool      `json:"accepted_answer"`
 }
 
 // User структура пользователя
@@ -68,15 +81,208 @@ type User struct {
 
 // ProcessedWebhook обработанные данные для отправки в Telegram
 type ProcessedWebhook struct {
-	Type       string // "topic" или "post"
-	TopicID    int
-	TopicTitle string
-	Category   string
-	CategoryID int // ID категории для маппинга на thread
-	Author     string
-	AuthorRole string // роль автора (admin, moderator, staff, user)
-	Content    string
-	Tags       []string
-	Summary    string
-	URL        string
+	Type         string   `json:"type"` // "topic" или "post"
+	TopicID      int      `json:"topic_id"`
+	PostNumber   int      `json:"post_number,omitempty"`
+	TopicTitle   string   `json:"topic_title"`
+	Category     string   `json:"category"`
+	CategoryID   int      `json:"category_id"` // ID категории для маппинга на thread
+	CategorySlug string   `json:"category_slug,omitempty"`
+	Author       string   `json:"author"`
+	AuthorRole   string   `json:"author_role"` // роль автора (admin, moderator, staff, user)
+	Content      string   `json:"content"`
+	Tags         []string `json:"tags"`
+	Summary      string   `json:"summary"`
+	URL          string   `json:"url"`
+	ImageURL     string   `json:"image_url,omitempty"` // первая картинка поста (абсолютная ссылка)
+}
+
+// WebhookEvent входящее событие Discourse вместе с метаданными из заголовков
+type WebhookEvent struct {
+	ID   string `json:"id"`   // X-Discourse-Event-Id
+	Type string `json:"type"` // X-Discourse-Event-Type (topic, post, ping, ...)
+	Name string `json:"name"` // X-Discourse-Event (topic_created, post_created, ...)
+	Body []byte `json:"body"`
+
+	Attempts int `json:"attempts,omitempty"` // неудачные попытки обработки
+}
+
+// OutgoingMessage полностью сформированное сообщение для Telegram
+type OutgoingMessage struct {
+	ChatID           int64  `json:"chat_id"`
+	ThreadID         int    `json:"thread_id,omitempty"`
+	ThreadMode       string `json:"thread_mode,omitempty"` // topic или reply
+	ReplyToMessageID int    `json:"reply_to_message_id,omitempty"`
+	Text             string `json:"text"`
+	ParseMode        string `json:"parse_mode,omitempty"`
+
+	Keyboard [][]InlineButton `json:"keyboard,omitempty"` // inline-кнопки со ссылками
+}
+
+// InlineButton кнопка под сообщением: ссылка или, если URL пуст, callback с данными CallbackData
+type InlineButton struct {
+	Text         string `json:"text"`
+	URL          string `json:"url,omitempty"`
+	CallbackData string `json:"callback_data,omitempty"`
+}
+
+// DeadLetter сообщение, которое не удалось доставить после всех повторов
+type DeadLetter struct {
+	ID        string          `json:"id"`
+	TopicID   int             `json:"topic_id"`
+	Message   OutgoingMessage `json:"message"`
+	Error     string          `json:"error"`
+	Attempts  int             `json:"attempts"`
+	CreatedAt time.Time       `json:"created_at"`
+
+	// Для анонса темы: после повторной отправки он сохраняется как Announcement
+	Destination string            `json:"destination,omitempty"`
+	Processed   *ProcessedWebhook `json:"processed,omitempty"`
+	IsPremium   bool              `json:"is_premium,omitempty"`
+}
+
+// Виды отправленных сообщений: текст правится через editMessageText, фото - через editMessageCaption
+const (
+	MessageKindText  = ""
+	MessageKindPhoto = "photo"
+)
+
+// MessageRef ссылка на отправленное в Telegram сообщение
+type MessageRef struct {
+	Destination string `json:"destination,omitempty"` // имя чата из конфигурации
+	ChatID      int64  `json:"chat_id"`
+	ThreadID    int    `json:"thread_id,omitempty"`
+	ThreadMode  string `json:"thread_mode,omitempty"`
+	MessageID   int    `json:"message_id"`
+	Kind        string `json:"kind,omitempty"` // MessageKindText или MessageKindPhoto
+
+	// Outdated сообщение не удалось обновить при последней правке темы; правка повторится
+	Outdated bool `json:"outdated,omitempty"`
+}
+
+// Announcement опубликованный анонс темы; хранится, чтобы его можно было обновить
+type Announcement struct {
+	TopicID   int              `json:"topic_id"`
+	Processed ProcessedWebhook `json:"processed"`
+	IsPremium bool             `json:"is_premium"`
+	Messages  []MessageRef     `json:"messages"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// Outdated сообщает, что часть сообщений анонса не обновилась при последней правке
+func (a *Announcement) Outdated() bool {
+	for _, ref := range a.Messages {
+		if ref.Outdated {
+			return true
+		}
+	}
+	return false
+}
+
+// Mute анонсы категории, отключенные командой /mute
+type Mute struct {
+	ChatID    int64     `json:"chat_id"`         // 0 - во всех чатах
+	Category  string    `json:"category"`        // ID или slug категории
+	Until     time.Time `json:"until,omitempty"` // нулевое время - бессрочно
+	CreatedBy int64     `json:"created_by"`      // Telegram ID администратора
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Active сообщает, что срок отключения еще не истек
+func (m *Mute) Active(now time.Time) bool {
+	return m.Until.IsZero() || now.Before(m.Until)
+}
+
+// Matches проверяет, относится ли отключение к категории анонса в чате chatID
+func (m *Mute) Matches(chatID int64, processed *ProcessedWebhook) bool {
+	if m.ChatID != 0 && m.ChatID != chatID {
+		return false
+	}
+	return matchesCategory(m.Category, processed)
+}
+
+// matchesCategory сравнивает ID или slug категории с категорией темы
+func matchesCategory(category string, processed *ProcessedWebhook) bool {
+	return category == strconv.Itoa(processed.CategoryID) ||
+		(processed.CategorySlug != "" && strings.EqualFold(category, processed.CategorySlug))
+}
+
+// Category категория форума
+type Category struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// Виды критериев личной подписки
+const (
+	SubscriptionCategory = "category"
+	SubscriptionTag      = "tag"
+	SubscriptionAuthor   = "author"
+	SubscriptionKeyword  = "keyword"
+)
+
+// Subscription личная подписка пользователя Telegram на темы форума.
+// Тема приходит, если совпал хотя бы один критерий
+type Subscription struct {
+	UserID     int64     `json:"user_id"` // совпадает с ID личного чата с ботом
+	Locale     string    `json:"locale,omitempty"`
+	Categories []string  `json:"categories,omitempty"` // ID или slug
+	Tags       []string  `json:"tags,omitempty"`
+	Authors    []string  `json:"authors,omitempty"`  // username на форуме
+	Keywords   []string  `json:"keywords,omitempty"` // ищутся в заголовке и тексте первого поста
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Values возвращает список критериев вида kind для чтения и изменения (nil для неизвестного вида)
+func (s *Subscription) Values(kind string) *[]string {
+	switch kind {
+	case SubscriptionCategory:
+		return &s.Categories
+	case SubscriptionTag:
+		return &s.Tags
+	case SubscriptionAuthor:
+		return &s.Authors
+	case SubscriptionKeyword:
+		return &s.Keywords
+	default:
+		return nil
+	}
+}
+
+// Empty сообщает, что в подписке нет ни одного критерия
+func (s *Subscription) Empty() bool {
+	return len(s.Categories) == 0 && len(s.Tags) == 0 && len(s.Authors) == 0 && len(s.Keywords) == 0
+}
+
+// Matches проверяет, подходит ли тема под подписку
+func (s *Subscription) Matches(processed *ProcessedWebhook) bool {
+	for _, category := range s.Categories {
+		if matchesCategory(category, processed) {
+			return true
+		}
+	}
+	for _, tag := range s.Tags {
+		for _, topicTag := range processed.Tags {
+			if strings.EqualFold(tag, topicTag) {
+				return true
+			}
+		}
+	}
+	for _, author := range s.Authors {
+		if strings.EqualFold(author, processed.Author) {
+			return true
+		}
+	}
+	if len(s.Keywords) > 0 {
+		text := strings.ToLower(processed.TopicTitle + "\n" + processed.Content)
+		for _, keyword := range s.Keywords {
+			if strings.Contains(text, strings.ToLower(keyword)) {
+				return true
+			}
+		}
+	}
+	return false
 }
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"webhook_tg_bot/internal/bot"
+
+	"github.com/gorilla/mux"
+)
+
+// requireSecret пропускает только запросы с секретом вебхука
+// в заголовке X-Webhook-Secret или параметре secret
+func (s *Server) requireSecret(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		secret := r.Header.Get("X-Webhook-Secret")
+		if secret == "" {
+			secret = r.URL.Query().Get("secret")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(s.config.WebhookSecret)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	letters, err := s.bot.DeadLetters()
+	if err != nil {
+		log.Printf("Error listing dead letters: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(letters)
+}
+
+func (s *Server) handleResendDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := s.bot.ResendDeadLetter(id); err != nil {
+		log.Printf("Error resending dead letter %s: %v", id, err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+func (s *Server) handleDeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	if err := s.storage.RemoveDeadLetter(id); err != nil {
+		log.Printf("Error deleting dead letter %s: %v", id, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// handleTelegramUpdate принимает обновления Telegram (BOT_UPDATES=webhook).
+// Запрос подписан secret_token, переданным в setWebhook
+func (s *Server) handleTelegramUpdate(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.config.BotWebhookSecret)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.bot.HandleWebhookUpdate(body); err != nil {
+		log.Printf("Error handling Telegram update: %v", err)
+		if errors.Is(err, bot.ErrUpdatesBusy) {
+			http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
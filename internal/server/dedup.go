@@ -0,0 +1,75 @@
+package server
+
+import (
+	"fmt"
+
+	"webhook_tg_bot/internal/models"
+)
+
+// claimEvent помечает событие как принятое. Повторная доставка того же
+// X-Discourse-Event-Id в пределах окна дедупликации вернет false
+func (s *Server) claimEvent(event *models.WebhookEvent) bool {
+	if s.config.DedupWindow == 0 || event.ID == "" {
+		return true
+	}
+	return s.storage.Claim(eventClaimKey(event.ID), s.config.DedupWindow)
+}
+
+// releaseEvent позволяет Discourse повторить событие, обработка которого не удалась
+func (s *Server) releaseEvent(event *models.WebhookEvent) {
+	if s.config.DedupWindow == 0 || event.ID == "" {
+		return
+	}
+	s.storage.Release(eventClaimKey(event.ID))
+}
+
+// claimTopic помечает тему как анонсированную
+func (s *Server) claimTopic(topicID int) bool {
+	return s.claim(topicClaimKey(topicID))
+}
+
+// releaseTopic снимает пометку, если анонс отправить не удалось
+func (s *Server) releaseTopic(topicID int) {
+	s.release(topicClaimKey(topicID))
+}
+
+// claimReply помечает ответ как анонсированный
+func (s *Server) claimReply(postID int, isAccepted bool) bool {
+	return s.claim(replyClaimKey(postID, isAccepted))
+}
+
+// releaseReply снимает пометку, если уведомление об ответе отправить не удалось
+func (s *Server) releaseReply(postID int, isAccepted bool) {
+	s.release(replyClaimKey(postID, isAccepted))
+}
+
+func (s *Server) claim(key string) bool {
+	if s.config.DedupWindow == 0 {
+		return true
+	}
+	return s.storage.Claim(key, s.config.DedupWindow)
+}
+
+func (s *Server) release(key string) {
+	if s.config.DedupWindow == 0 {
+		return
+	}
+	s.storage.Release(key)
+}
+
+func eventClaimKey(eventID string) string {
+	return "event:" + eventID
+}
+
+func topicClaimKey(topicID int) string {
+	return fmt.Sprintf("topic:%d", topicID)
+}
+
+// replyClaimKey ключ уведомления об ответе. У отметки решения свой ключ:
+// ответ, уже отправленный как обычный, должен прийти еще раз как решение
+func replyClaimKey(postID int, isAccepted bool) string {
+	if isAccepted {
+		return fmt.Sprintf("solution:%d", postID)
+	}
+	return fmt.Sprintf("reply:%d", postID)
+}
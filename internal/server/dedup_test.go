@@ -0,0 +1,76 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/models"
+	"webhook_tg_bot/internal/storage"
+)
+
+func dedupServer(t *testing.T, window time.Duration) *Server {
+	store := storage.NewMemoryStorage(time.Hour, 0)
+	t.Cleanup(func() { store.Close() })
+	return &Server{config: &config.Config{DedupWindow: window}, storage: store}
+}
+
+func TestClaimEvent(t *testing.T) {
+	s := dedupServer(t, time.Hour)
+	event := &models.WebhookEvent{ID: "abc"}
+
+	if !s.claimEvent(event) {
+		t.Fatal("first delivery of an event was rejected")
+	}
+	if s.claimEvent(&models.WebhookEvent{ID: "abc"}) {
+		t.Error("repeated X-Discourse-Event-Id was accepted")
+	}
+	if !s.claimEvent(&models.WebhookEvent{ID: "def"}) {
+		t.Error("other event was rejected")
+	}
+
+	// После ошибки обработки Discourse может доставить событие заново
+	s.releaseEvent(event)
+	if !s.claimEvent(event) {
+		t.Error("released event was rejected")
+	}
+
+	// Без ID дедупликация невозможна
+	if !s.claimEvent(&models.WebhookEvent{}) || !s.claimEvent(&models.WebhookEvent{}) {
+		t.Error("event without ID was rejected")
+	}
+}
+
+func TestClaimDisabled(t *testing.T) {
+	s := dedupServer(t, 0)
+
+	for i := 0; i < 2; i++ {
+		if !s.claimEvent(&models.WebhookEvent{ID: "abc"}) || !s.claimTopic(42) {
+			t.Fatal("DEDUP_WINDOW=0 still rejects repeated deliveries")
+		}
+	}
+}
+
+func TestClaimTopicAndReply(t *testing.T) {
+	s := dedupServer(t, time.Hour)
+
+	if !s.claimTopic(42) || s.claimTopic(42) {
+		t.Error("topic announced twice")
+	}
+	s.releaseTopic(42)
+	if !s.claimTopic(42) {
+		t.Error("released topic was rejected")
+	}
+
+	// Ответ, отмеченный решением, анонсируется еще раз, но только однажды
+	if !s.claimReply(7, false) || s.claimReply(7, false) {
+		t.Error("reply announced twice")
+	}
+	if !s.claimReply(7, true) || s.claimReply(7, true) {
+		t.Error("solution must be claimed once, independently of the reply")
+	}
+	// Тема и пост с одинаковым ID не мешают друг другу
+	if !s.claimReply(42, false) {
+		t.Error("reply claim collides with the topic claim")
+	}
+}
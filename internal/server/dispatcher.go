@@ -0,0 +1,44 @@
+package server
+
+import (
+	"log"
+
+	"webhook_tg_bot/internal/models"
+)
+
+// EventHandler обработчик конкретного события Discourse
+type EventHandler func(event *models.WebhookEvent) error
+
+// Dispatcher направляет события Discourse зарегистрированным обработчикам
+// по заголовкам X-Discourse-Event-Type и X-Discourse-Event
+type Dispatcher struct {
+	handlers map[string]EventHandler
+}
+
+// NewDispatcher создает пустой реестр обработчиков
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		handlers: make(map[string]EventHandler),
+	}
+}
+
+// Register регистрирует обработчик для пары (тип события, событие)
+func (d *Dispatcher) Register(eventType, eventName string, handler EventHandler) {
+	d.handlers[eventKey(eventType, eventName)] = handler
+}
+
+// Dispatch вызывает обработчик события. Неизвестные события только логируются,
+// чтобы Discourse не считал endpoint сбойным
+func (d *Dispatcher) Dispatch(event *models.WebhookEvent) error {
+	handler, exists := d.handlers[eventKey(event.Type, event.Name)]
+	if !exists {
+		log.Printf("Ignoring unhandled webhook event: type=%q event=%q id=%q", event.Type, event.Name, event.ID)
+		return nil
+	}
+
+	return handler(event)
+}
+
+func eventKey(eventType, eventName string) string {
+	return eventType + "/" + eventName
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"webhook_tg_bot/internal/models"
+)
+
+func TestDispatcherDispatch(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	var called string
+	dispatcher := NewDispatcher()
+	dispatcher.Register("topic", "topic_created", func(event *models.WebhookEvent) error {
+		called = "topic_created"
+		return nil
+	})
+	dispatcher.Register("post", "post_created", func(event *models.WebhookEvent) error {
+		called = "post_created"
+		return errFailed
+	})
+
+	tests := []struct {
+		name       string
+		event      models.WebhookEvent
+		wantCalled string
+		wantErr    error
+	}{
+		{"topic event", models.WebhookEvent{Type: "topic", Name: "topic_created"}, "topic_created", nil},
+		{"handler error returned", models.WebhookEvent{Type: "post", Name: "post_created"}, "post_created", errFailed},
+		{"unknown event ignored", models.WebhookEvent{Type: "user", Name: "user_created"}, "", nil},
+		{"type must match too", models.WebhookEvent{Type: "post", Name: "topic_created"}, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = ""
+			err := dispatcher.Dispatch(&tt.event)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Dispatch() error = %v, want %v", err, tt.wantErr)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("Dispatch() called %q, want %q", called, tt.wantCalled)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+
+	"webhook_tg_bot/internal/models"
+	"webhook_tg_bot/internal/storage"
+)
+
+func (s *Server) handleTopicEdited(event *models.WebhookEvent) error {
+	var topicWebhook models.WebhookTopic
+	if err := json.Unmarshal(event.Body, &topicWebhook); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %v", event.Name, err)
+	}
+	topic := &topicWebhook.Topic
+
+	announcement, err := s.storage.GetAnnouncement(topic.ID)
+	if err != nil {
+		return err
+	}
+	if announcement == nil {
+		log.Printf("Skipping edit of topic %d - no announcement to update", topic.ID)
+		return nil
+	}
+
+	// Тема скрыта, удалена или перенесена в неотслеживаемую категорию
+	if reason := s.removalReason(topic, announcement); reason != "" {
+		s.storage.RemoveTopic(topic.ID)
+		log.Printf("Removing announcement of topic %d - %s", topic.ID, reason)
+		return s.bot.RemoveAnnouncement(announcement)
+	}
+
+	processed := &announcement.Processed
+	unchanged := processed.TopicTitle == topic.Title && processed.CategoryID == topic.CategoryID && slices.Equal(processed.Tags, topic.Tags)
+	if unchanged && !announcement.Outdated() {
+		log.Printf("Skipping edit of topic %d - title, category and tags unchanged", topic.ID)
+		return nil
+	}
+
+	processed.TopicTitle = topic.Title
+	processed.Tags = topic.Tags
+	processed.URL = s.topicURL(topic.Slug, topic.ID)
+	if processed.CategoryID != topic.CategoryID {
+		processed.CategoryID = topic.CategoryID
+		processed.CategorySlug = ""
+		processed.Category = s.getCategoryName(&storage.TopicData{Topic: topic})
+	}
+
+	log.Printf("Updating announcement of topic %d after %s", topic.ID, event.Name)
+	return s.bot.EditAnnouncement(announcement)
+}
+
+func (s *Server) handleTopicDestroyed(event *models.WebhookEvent) error {
+	var topicWebhook models.WebhookTopic
+	if err := json.Unmarshal(event.Body, &topicWebhook); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %v", event.Name, err)
+	}
+	topic := &topicWebhook.Topic
+
+	// Тема могла еще не успеть попасть в анонс
+	s.storage.RemoveTopic(topic.ID)
+
+	announcement, err := s.storage.GetAnnouncement(topic.ID)
+	if err != nil {
+		return err
+	}
+	if announcement == nil {
+		return nil
+	}
+
+	log.Printf("Removing announcement of topic %d - topic deleted", topic.ID)
+	return s.bot.RemoveAnnouncement(announcement)
+}
+
+// removalReason возвращает причину снять анонс темы или пустую строку
+func (s *Server) removalReason(topic *models.Topic, announcement *models.Announcement) string {
+	switch {
+	case topic.DeletedAt != nil:
+		return "topic deleted"
+	case topic.IsHidden():
+		return "topic unlisted"
+	case !s.config.ShouldMonitorCategory(topic.CategoryID) && !s.watched(topic, announcement):
+		return fmt.Sprintf("moved to ignored category %d", topic.CategoryID)
+	default:
+		return ""
+	}
+}
+
+// watched проверяет, отправляет ли измененную тему в свой чат правило наблюдения
+func (s *Server) watched(topic *models.Topic, announcement *models.Announcement) bool {
+	processed := announcement.Processed
+	processed.TopicTitle = topic.Title
+	processed.Tags = topic.Tags
+	processed.CategoryID = topic.CategoryID
+	return s.config.RoutedByWatch(&processed)
+}
+
+func (s *Server) handlePostEdited(event *models.WebhookEvent) error {
+	var postWebhook models.WebhookPost
+	if err := json.Unmarshal(event.Body, &postWebhook); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %v", event.Name, err)
+	}
+	post := &postWebhook.Post
+
+	// Анонс строится из первого поста темы
+	if post.PostNumber != 1 {
+		return nil
+	}
+
+	announcement, err := s.storage.GetAnnouncement(post.TopicID)
+	if err != nil {
+		return err
+	}
+	if announcement == nil {
+		log.Printf("Skipping edit of post %d - topic %d has no announcement", post.ID, post.TopicID)
+		return nil
+	}
+
+	processed := &announcement.Processed
+	if processed.Content == post.Raw && !announcement.Outdated() {
+		log.Printf("Skipping edit of post %d - content unchanged", post.ID)
+		return nil
+	}
+
+	processed.Content = post.Raw
+	if post.TopicTitle != "" {
+		processed.TopicTitle = post.TopicTitle
+	}
+
+	log.Printf("Updating announcement of topic %d after %s", post.TopicID, event.Name)
+	return s.bot.EditAnnouncement(announcement)
+}
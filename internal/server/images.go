@@ -0,0 +1,57 @@
+package server
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	imgTagPattern  = regexp.MustCompile(`(?is)<img\s[^>]*>`)
+	imgSrcPattern  = regexp.MustCompile(`(?is)\ssrc\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	imgSkipPattern = regexp.MustCompile(`(?is)class\s*=\s*["'][^"']*\b(emoji|avatar|site-icon|onebox-avatar)\b`)
+)
+
+// firstImageURL возвращает первую картинку из Cooked HTML поста. Эмодзи и аватарки пропускаются,
+// относительные ссылки на загрузки (/uploads/..., //cdn/...) дополняются до абсолютных по baseURL
+func firstImageURL(cooked, baseURL string) string {
+	for _, tag := range imgTagPattern.FindAllString(cooked, -1) {
+		if imgSkipPattern.MatchString(tag) {
+			continue
+		}
+
+		match := imgSrcPattern.FindStringSubmatch(tag)
+		if match == nil {
+			continue
+		}
+		src := strings.TrimSpace(html.UnescapeString(match[1] + match[2]))
+		if src == "" || strings.HasPrefix(src, "data:") {
+			continue
+		}
+
+		if resolved := resolveURL(src, baseURL); resolved != "" {
+			return resolved
+		}
+	}
+	return ""
+}
+
+// resolveURL приводит ссылку к абсолютной http(s)-ссылке относительно baseURL
+func resolveURL(src, baseURL string) string {
+	ref, err := url.Parse(src)
+	if err != nil {
+		return ""
+	}
+	if !ref.IsAbs() {
+		base, err := url.Parse(strings.TrimRight(baseURL, "/") + "/")
+		if err != nil || base.Host == "" {
+			return ""
+		}
+		ref = base.ResolveReference(ref)
+	}
+	if ref.Scheme != "http" && ref.Scheme != "https" {
+		return ""
+	}
+	return ref.String()
+}
@@ -0,0 +1,31 @@
+package server
+
+import "testing"
+
+func TestFirstImageURL(t *testing.T) {
+	const baseURL = "https://forum.example.com"
+
+	tests := []struct {
+		name   string
+		cooked string
+		want   string
+	}{
+		{"no images", "<p>text</p>", ""},
+		{"absolute url", `<p><img src="https://cdn.example.com/a.png"></p>`, "https://cdn.example.com/a.png"},
+		{"relative upload", `<img src="/uploads/default/original/1X/a.jpg" alt="a">`, baseURL + "/uploads/default/original/1X/a.jpg"},
+		{"protocol-relative url", `<img src="//cdn.example.com/a.png">`, "https://cdn.example.com/a.png"},
+		{"single quotes and entities", `<img alt='x' src='/a.png?w=1&amp;h=2'>`, baseURL + "/a.png?w=1&h=2"},
+		{"emoji skipped", `<img src="/images/emoji/smile.png" class="emoji"><img src="/b.png">`, baseURL + "/b.png"},
+		{"avatar skipped", `<img class="avatar" src="/user/1.png">`, ""},
+		{"data url skipped", `<img src="data:image/png;base64,AAAA"><img src="/c.png">`, baseURL + "/c.png"},
+		{"unsupported scheme", `<img src="ftp://example.com/a.png">`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := firstImageURL(tt.cooked, baseURL); got != tt.want {
+				t.Errorf("firstImageURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
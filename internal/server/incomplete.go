@@ -0,0 +1,83 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	"webhook_tg_bot/internal/metrics"
+	"webhook_tg_bot/internal/models"
+	"webhook_tg_bot/internal/storage"
+)
+
+// incompleteCheckInterval как часто проверяем темы, для которых пришла только половина данных
+const incompleteCheckInterval = 15 * time.Second
+
+// incompleteLoop периодически отправляет темы, не получившие пару за grace period
+func (s *Server) incompleteLoop() {
+	ticker := time.NewTicker(incompleteCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			for _, data := range s.storage.TakeIncomplete(s.config.IncompleteGracePeriod) {
+				// Повтора нет: сообщения, которые Telegram не принял, уже сохранены в dead letters
+				if err := s.deliverIncomplete(data); err != nil {
+					log.Printf("Error delivering incomplete topic: %v", err)
+					metrics.Inc("failed_incomplete")
+				}
+			}
+		}
+	}
+}
+
+// deliverIncomplete дополняет неполные данные и отправляет уведомление
+func (s *Server) deliverIncomplete(data *storage.TopicData) error {
+	var path string
+
+	switch {
+	case data.Topic != nil:
+		path = "excerpt"
+		if s.discourse.Enabled() {
+			post, err := s.discourse.GetFirstPost(data.Topic.ID)
+			if err != nil {
+				log.Printf("Failed to fetch first post of topic %d: %v", data.Topic.ID, err)
+			} else {
+				data.Post = post
+				path = "api"
+			}
+		}
+	case data.Post != nil:
+		path = "post_only"
+		data.Topic = topicFromPost(data.Post)
+	default:
+		return nil
+	}
+
+	log.Printf("Delivering incomplete topic %d via %s fallback", data.Topic.ID, path)
+	metrics.Inc("delivery_fallback_" + path)
+
+	return s.sendNotification(data)
+}
+
+// topicFromPost восстанавливает основные данные темы из первого поста
+func topicFromPost(post *models.Post) *models.Topic {
+	return &models.Topic{
+		ID:         post.TopicID,
+		Title:      post.TopicTitle,
+		CategoryID: post.CategoryID,
+		UserID:     post.UserID,
+		Slug:       post.TopicSlug,
+		CreatedAt:  post.CreatedAt,
+		CreatedBy: models.User{
+			ID:         post.UserID,
+			Username:   post.Username,
+			Admin:      post.Admin,
+			Moderator:  post.Moderator,
+			Staff:      post.Staff,
+			TrustLevel: post.TrustLevel,
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/discourse"
+	"webhook_tg_bot/internal/models"
+	"webhook_tg_bot/internal/storage"
+)
+
+// incompleteServer собирает сервер без бота: тесты останавливаются до отправки в Telegram
+func incompleteServer(t *testing.T, cfg *config.Config) *Server {
+	store := storage.NewMemoryStorage(time.Hour, 0)
+	t.Cleanup(func() { store.Close() })
+	return &Server{config: cfg, storage: store, discourse: discourse.NewClient(cfg)}
+}
+
+func TestDeliverIncompletePostOnly(t *testing.T) {
+	// Категория не отслеживается, поэтому уведомление не уходит дальше фильтра
+	s := incompleteServer(t, &config.Config{IgnoredCategories: []int{5}})
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	data := &storage.TopicData{Post: &models.Post{
+		TopicID:    42,
+		TopicTitle: "Cluster is down",
+		TopicSlug:  "cluster-is-down",
+		CategoryID: 5,
+		CreatedAt:  created,
+		UserID:     7,
+		Username:   "alice",
+		Moderator:  true,
+		TrustLevel: 3,
+	}}
+
+	if err := s.deliverIncomplete(data); err != nil {
+		t.Fatalf("deliverIncomplete() error = %v", err)
+	}
+
+	topic := data.Topic
+	if topic == nil {
+		t.Fatal("deliverIncomplete() did not restore the topic from the post")
+	}
+	if topic.ID != 42 || topic.Title != "Cluster is down" || topic.Slug != "cluster-is-down" ||
+		topic.CategoryID != 5 || !topic.CreatedAt.Equal(created) {
+		t.Errorf("restored topic = %+v", topic)
+	}
+	if topic.CreatedBy.ID != 7 || topic.CreatedBy.Username != "alice" || !topic.CreatedBy.Moderator {
+		t.Errorf("restored author = %+v", topic.CreatedBy)
+	}
+}
+
+func TestDeliverIncompleteFetchesFirstPost(t *testing.T) {
+	var requested string
+	forum := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		json.NewEncoder(w).Encode(models.Post{TopicID: 42, Raw: "full text", CategorySlug: "ops"})
+	}))
+	defer forum.Close()
+
+	s := incompleteServer(t, &config.Config{BaseURL: forum.URL, DiscourseAPIKey: "key", DedupWindow: time.Hour})
+	// Тема уже анонсирована: сервер дойдет до проверки дубликата и не станет обращаться к боту
+	s.claimTopic(42)
+
+	data := &storage.TopicData{Topic: &models.Topic{ID: 42, Title: "Cluster is down", Excerpt: "short"}}
+	if err := s.deliverIncomplete(data); err != nil {
+		t.Fatalf("deliverIncomplete() error = %v", err)
+	}
+	if requested != "/posts/by_number/42/1.json" {
+		t.Errorf("first post requested from %q", requested)
+	}
+	if data.Post == nil || data.Post.Raw != "full text" {
+		t.Errorf("deliverIncomplete() post = %+v, want the one loaded from the API", data.Post)
+	}
+}
+
+func TestDeliverIncompleteWithoutAPI(t *testing.T) {
+	s := incompleteServer(t, &config.Config{DedupWindow: time.Hour})
+	s.claimTopic(42)
+
+	data := &storage.TopicData{Topic: &models.Topic{ID: 42, Title: "Cluster is down", Excerpt: "short"}}
+	if err := s.deliverIncomplete(data); err != nil {
+		t.Fatalf("deliverIncomplete() error = %v", err)
+	}
+	if data.Post != nil {
+		t.Errorf("deliverIncomplete() loaded a post without DISCOURSE_API_KEY")
+	}
+	if got := s.processedFromData(data).Content; got != "short" {
+		t.Errorf("content = %q, want the topic excerpt", got)
+	}
+}
+
+func TestDeliverIncompleteEmpty(t *testing.T) {
+	s := incompleteServer(t, &config.Config{})
+	if err := s.deliverIncomplete(&storage.TopicData{}); err != nil {
+		t.Errorf("deliverIncomplete() error = %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"webhook_tg_bot/internal/metrics"
+	"webhook_tg_bot/internal/models"
+)
+
+// Queue ограниченная очередь событий, которую разбирает пул воркеров
+type Queue struct {
+	jobs    chan *models.WebhookEvent
+	stop    chan struct{}
+	handler EventHandler
+	wg      sync.WaitGroup
+	mutex   sync.RWMutex
+	closed  bool
+	active  atomic.Int32 // события, которые сейчас обрабатываются
+}
+
+// NewQueue создает очередь заданного размера
+func NewQueue(size int, handler EventHandler) *Queue {
+	return &Queue{
+		jobs:    make(chan *models.WebhookEvent, size),
+		stop:    make(chan struct{}),
+		handler: handler,
+	}
+}
+
+// Start запускает воркеры
+func (q *Queue) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.worker(i + 1)
+	}
+	log.Printf("Started %d webhook workers (queue size %d)", workers, cap(q.jobs))
+}
+
+// Enqueue ставит событие в очередь без блокировки. Возвращает false, если очередь заполнена
+// или уже остановлена
+func (q *Queue) Enqueue(event *models.WebhookEvent) bool {
+	q.mutex.RLock()
+	defer q.mutex.RUnlock()
+
+	if q.closed {
+		return false
+	}
+
+	select {
+	case q.jobs <- event:
+		metrics.Set("queue_length", int64(len(q.jobs)))
+		return true
+	default:
+		return false
+	}
+}
+
+// Len возвращает количество событий, ожидающих обработки
+func (q *Queue) Len() int {
+	return len(q.jobs)
+}
+
+// Shutdown перестает принимать события и ждет, пока воркеры разберут очередь.
+// Если ctx истекает раньше, воркеры останавливаются после текущего события,
+// а необработанные события возвращаются вызывающему. Shutdown всегда дожидается
+// событий, которые уже обрабатываются, чтобы хранилище не закрылось под ними
+func (q *Queue) Shutdown(ctx context.Context) []*models.WebhookEvent {
+	q.mutex.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		close(q.stop)
+
+		var pending []*models.WebhookEvent
+		for event := range q.jobs {
+			pending = append(pending, event)
+		}
+
+		log.Printf("Shutdown timeout reached, waiting for %d events in progress", q.active.Load())
+		<-done
+		return pending
+	}
+}
+
+func (q *Queue) worker(id int) {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case event, ok := <-q.jobs:
+			if !ok {
+				return
+			}
+
+			metrics.Set("queue_length", int64(len(q.jobs)))
+			q.active.Add(1)
+			if err := q.handler(event); err != nil {
+				log.Printf("Worker %d: error processing %s event %s: %v", id, event.Name, event.ID, err)
+				metrics.Inc("processing_errors")
+			}
+			q.active.Add(-1)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"webhook_tg_bot/internal/models"
+)
+
+func TestQueueProcessesEvents(t *testing.T) {
+	var (
+		mutex     sync.Mutex
+		processed []string
+	)
+	queue := NewQueue(10, func(event *models.WebhookEvent) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		processed = append(processed, event.ID)
+		return nil
+	})
+	queue.Start(2)
+
+	for _, id := range []string{"a", "b", "c"} {
+		if !queue.Enqueue(&models.WebhookEvent{ID: id}) {
+			t.Fatalf("Enqueue(%s) = false", id)
+		}
+	}
+
+	if pending := queue.Shutdown(context.Background()); len(pending) != 0 {
+		t.Errorf("Shutdown() returned %d pending events after draining", len(pending))
+	}
+	if len(processed) != 3 {
+		t.Errorf("processed %v, want all three events", processed)
+	}
+	if queue.Enqueue(&models.WebhookEvent{ID: "late"}) {
+		t.Error("stopped queue accepted an event")
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	// Воркеры не запущены, поэтому очередь заполняется
+	queue := NewQueue(1, func(*models.WebhookEvent) error { return nil })
+
+	if !queue.Enqueue(&models.WebhookEvent{ID: "a"}) {
+		t.Fatal("Enqueue() into an empty queue = false")
+	}
+	if queue.Enqueue(&models.WebhookEvent{ID: "b"}) {
+		t.Error("Enqueue() into a full queue = true")
+	}
+}
+
+func TestRetryEvent(t *testing.T) {
+	s := &Server{queue: NewQueue(1, func(*models.WebhookEvent) error { return nil })}
+	s.retries.timers = make(map[*models.WebhookEvent]*time.Timer)
+
+	exhausted := &models.WebhookEvent{ID: "a", Attempts: maxEventAttempts - 1}
+	if s.retryEvent(exhausted) {
+		t.Errorf("retryEvent() scheduled attempt %d of %d", exhausted.Attempts+1, maxEventAttempts)
+	}
+
+	event := &models.WebhookEvent{ID: "b"}
+	if !s.retryEvent(event) || event.Attempts != 1 {
+		t.Fatalf("retryEvent() did not schedule the first retry (attempts = %d)", event.Attempts)
+	}
+
+	// При остановке запланированные повторы отменяются и возвращаются для сохранения
+	if taken := s.takeRetries(); len(taken) != 1 || taken[0] != event {
+		t.Errorf("takeRetries() = %v, want event b", taken)
+	}
+	if s.retryEvent(&models.WebhookEvent{ID: "c"}) {
+		t.Error("retryEvent() scheduled a retry after shutdown")
+	}
+}
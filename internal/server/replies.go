@@ -0,0 +1,84 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"webhook_tg_bot/internal/bot"
+	"webhook_tg_bot/internal/models"
+)
+
+func (s *Server) handleAcceptedSolution(event *models.WebhookEvent) error {
+	var postWebhook models.WebhookPost
+	if err := json.Unmarshal(event.Body, &postWebhook); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %v", event.Name, err)
+	}
+	return s.processReply(&postWebhook.Post, true)
+}
+
+// processReply отправляет уведомление об ответе ответом на анонс темы
+func (s *Server) processReply(post *models.Post, isAccepted bool) error {
+	if !s.config.ReplyNotifications {
+		log.Printf("Skipping post %d - not the first post in topic", post.ID)
+		return nil
+	}
+
+	// Проверяем, нужно ли игнорировать этого пользователя
+	if s.config.ShouldIgnoreUser(post.UserID) {
+		log.Printf("Skipping reply %d - user %d is ignored", post.ID, post.UserID)
+		return nil
+	}
+
+	// Проверяем, нужно ли отслеживать эту категорию
+	if !s.config.ShouldMonitorCategory(post.CategoryID) {
+		log.Printf("Skipping reply %d - category %d is not monitored or is ignored", post.ID, post.CategoryID)
+		return nil
+	}
+
+	isStaff := post.Admin || post.Moderator || post.Staff
+	if !s.config.ShouldNotifyReply(post.CategoryID, isStaff, isAccepted, post.TopicPostsCount) {
+		log.Printf("Skipping reply %d - does not match reply rules for category %d", post.ID, post.CategoryID)
+		return nil
+	}
+
+	// Ответ публикуется под анонсом темы, поэтому без анонса его некуда прикрепить
+	announcement, err := s.storage.GetAnnouncement(post.TopicID)
+	if err != nil {
+		return err
+	}
+	if announcement == nil {
+		log.Printf("Skipping reply %d - topic %d has no announcement", post.ID, post.TopicID)
+		return nil
+	}
+
+	if !s.claimReply(post.ID, isAccepted) {
+		log.Printf("Skipping reply %d - already announced", post.ID)
+		return nil
+	}
+
+	topic := announcement.Processed
+	processed := &models.ProcessedWebhook{
+		Type:       "reply",
+		TopicID:    post.TopicID,
+		PostNumber: post.PostNumber,
+		TopicTitle: topic.TopicTitle,
+		Category:   topic.Category,
+		CategoryID: post.CategoryID,
+		Author:     post.Username,
+		AuthorRole: s.getUserRole(models.User{}, post),
+		Content:    post.Raw,
+		Tags:       topic.Tags,
+		URL:        fmt.Sprintf("%s/%d", topic.URL, post.PostNumber),
+	}
+
+	if err := s.bot.SendReplyNotification(processed, announcement, isAccepted); err != nil {
+		// Если часть чатов ответ получила, а остальные сообщения в dead letters, ответ считается отправленным
+		if !errors.Is(err, bot.ErrDeadLettered) {
+			s.releaseReply(post.ID, isAccepted)
+		}
+		return err
+	}
+	return nil
+}
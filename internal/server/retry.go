@@ -0,0 +1,78 @@
+package server
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"webhook_tg_bot/internal/metrics"
+	"webhook_tg_bot/internal/models"
+)
+
+const (
+	// maxEventAttempts сколько раз обрабатывается событие, прежде чем от него отказаться
+	maxEventAttempts = 3
+	// eventRetryDelay задержка перед первым повтором, удваивается с каждой попыткой
+	eventRetryDelay = time.Minute
+)
+
+// eventRetries события, ожидающие повторной обработки после ошибки
+type eventRetries struct {
+	mutex  sync.Mutex
+	timers map[*models.WebhookEvent]*time.Timer
+	closed bool
+}
+
+// retryEvent планирует повторную обработку события, если попытки еще не исчерпаны.
+// Возвращает false, если событие больше не будет обработано
+func (s *Server) retryEvent(event *models.WebhookEvent) bool {
+	event.Attempts++
+	if event.Attempts >= maxEventAttempts {
+		log.Printf("Giving up %s event %s after %d attempts", event.Name, event.ID, event.Attempts)
+		metrics.Inc("failed_events")
+		return false
+	}
+
+	s.retries.mutex.Lock()
+	defer s.retries.mutex.Unlock()
+	if s.retries.closed {
+		return false
+	}
+
+	delay := eventRetryDelay << (event.Attempts - 1)
+	log.Printf("Retrying %s event %s in %s (attempt %d of %d)", event.Name, event.ID, delay, event.Attempts+1, maxEventAttempts)
+	metrics.Inc("retried_events")
+
+	s.retries.timers[event] = time.AfterFunc(delay, func() {
+		s.retries.mutex.Lock()
+		defer s.retries.mutex.Unlock()
+
+		// Событие уже забрано при остановке
+		if _, waiting := s.retries.timers[event]; !waiting {
+			return
+		}
+		delete(s.retries.timers, event)
+
+		if !s.queue.Enqueue(event) {
+			log.Printf("Webhook queue is full, dropping retry of %s event %s", event.Name, event.ID)
+			metrics.Inc("failed_events")
+			s.releaseEvent(event)
+		}
+	})
+	return true
+}
+
+// takeRetries отменяет запланированные повторы и возвращает их события, чтобы сохранить при остановке
+func (s *Server) takeRetries() []*models.WebhookEvent {
+	s.retries.mutex.Lock()
+	defer s.retries.mutex.Unlock()
+
+	s.retries.closed = true
+	events := make([]*models.WebhookEvent, 0, len(s.retries.timers))
+	for event, timer := range s.retries.timers {
+		timer.Stop()
+		events = append(events, event)
+	}
+	s.retries.timers = nil
+	return events
+}
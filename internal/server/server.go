@@ -1,18 +1,23 @@
 package server
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"webhook_tg_bot/internal/bot"
 	"webhook_tg_bot/internal/config"
+	"webhook_tg_bot/internal/discourse"
+	"webhook_tg_bot/internal/metrics"
 	"webhook_tg_bot/internal/models"
 	"webhook_tg_bot/internal/storage"
 
@@ -20,31 +25,134 @@ import (
 )
 
 type Server struct {
-	config  *config.Config
-	bot     *bot.TelegramBot
-	router  *mux.Router
-	storage *storage.MemoryStorage
+	config     *config.Config
+	bot        *bot.TelegramBot
+	router     *mux.Router
+	storage    storage.Storage
+	dispatcher *Dispatcher
+	discourse  *discourse.Client
+	queue      *Queue
+	retries    eventRetries
+	httpServer *http.Server
+	stop       chan struct{} // останавливает фоновые циклы сервера
 }
 
-func New(cfg *config.Config, bot *bot.TelegramBot) *Server {
+func New(cfg *config.Config, bot *bot.TelegramBot, store storage.Storage) *Server {
 	s := &Server{
-		config:  cfg,
-		bot:     bot,
-		router:  mux.NewRouter(),
-		storage: storage.NewMemoryStorage(),
+		config:     cfg,
+		bot:        bot,
+		router:     mux.NewRouter(),
+		storage:    store,
+		dispatcher: NewDispatcher(),
+		discourse:  discourse.NewClient(cfg),
+		stop:       make(chan struct{}),
 	}
+	s.retries.timers = make(map[*models.WebhookEvent]*time.Timer)
+	s.queue = NewQueue(cfg.QueueSize, s.processEvent)
+	s.bot.SetTopicLoader(s.loadTopic)
 
 	s.setupRoutes()
+	s.setupEventHandlers()
+
+	s.httpServer = &http.Server{
+		Addr:    ":" + cfg.WebhookPort,
+		Handler: s.router,
+	}
 	return s
 }
 
 func (s *Server) setupRoutes() {
 	s.router.HandleFunc(s.config.WebhookPath, s.handleWebhook).Methods("POST")
 	s.router.HandleFunc("/health", s.handleHealth).Methods("GET")
+	s.router.HandleFunc("/metrics", metrics.Handler).Methods("GET")
+
+	// Недоставленные сообщения
+	s.router.HandleFunc("/dead-letters", s.requireSecret(s.handleListDeadLetters)).Methods("GET")
+	s.router.HandleFunc("/dead-letters/{id}/resend", s.requireSecret(s.handleResendDeadLetter)).Methods("POST")
+	s.router.HandleFunc("/dead-letters/{id}", s.requireSecret(s.handleDeleteDeadLetter)).Methods("DELETE")
+
+	// Обновления Telegram (команды администраторов) в режиме BOT_UPDATES=webhook
+	if s.config.BotUpdates == config.BotUpdatesWebhook {
+		s.router.HandleFunc(s.config.BotWebhookPath, s.handleTelegramUpdate).Methods("POST")
+	}
+}
+
+func (s *Server) setupEventHandlers() {
+	s.dispatcher.Register("ping", "ping", s.handlePing)
+	s.dispatcher.Register("topic", "topic_created", s.handleTopicCreated)
+	s.dispatcher.Register("post", "post_created", s.handlePostCreated)
+	s.dispatcher.Register("topic", "topic_edited", s.handleTopicEdited)
+	s.dispatcher.Register("post", "post_edited", s.handlePostEdited)
+	s.dispatcher.Register("topic", "topic_destroyed", s.handleTopicDestroyed)
+	s.dispatcher.Register("solved", "accepted_solution", s.handleAcceptedSolution)
 }
 
 func (s *Server) Start() error {
-	return http.ListenAndServe(":"+s.config.WebhookPort, s.router)
+	s.queue.Start(s.config.WorkerCount)
+	s.replayPendingEvents()
+
+	if s.config.IncompleteGracePeriod > 0 {
+		go s.incompleteLoop()
+	}
+
+	// Без команд бот продолжает отправлять анонсы
+	if err := s.bot.StartUpdates(); err != nil {
+		log.Printf("Failed to start receiving Telegram updates, commands are disabled: %v", err)
+	}
+
+	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown перестает принимать вебхуки и дожидается обработки очереди.
+// События, которые не успели обработать до истечения ctx, сохраняются в хранилище
+// и будут обработаны при следующем запуске
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.httpServer.Shutdown(ctx)
+	if err != nil {
+		log.Printf("Error shutting down HTTP server: %v", err)
+	}
+
+	close(s.stop)
+	s.bot.StopUpdates(ctx)
+
+	// Запланированные повторы сохраняются вместе с необработанными событиями
+	retries := s.takeRetries()
+	pending := append(s.queue.Shutdown(ctx), retries...)
+	switch {
+	case len(pending) == 0:
+	case !s.storage.Persistent():
+		// Хранилище в памяти исчезнет вместе с процессом
+		log.Printf("Dropping %d unprocessed webhook events: %s storage does not survive restarts, use STORAGE_TYPE=bolt to keep them", len(pending), s.config.StorageType)
+		metrics.Add("dropped_events", int64(len(pending)))
+	default:
+		log.Printf("Saving %d unprocessed webhook events for the next start", len(pending))
+		if saveErr := s.storage.SavePendingEvents(pending); saveErr != nil {
+			return fmt.Errorf("failed to save pending events: %v", saveErr)
+		}
+	}
+
+	return err
+}
+
+// replayPendingEvents ставит в очередь события, сохраненные при прошлой остановке
+func (s *Server) replayPendingEvents() {
+	events, err := s.storage.TakePendingEvents()
+	if err != nil {
+		log.Printf("Error loading pending events: %v", err)
+		return
+	}
+
+	for _, event := range events {
+		if !s.queue.Enqueue(event) {
+			log.Printf("Queue is full, dropping pending %s event %s", event.Name, event.ID)
+		}
+	}
+	if len(events) > 0 {
+		log.Printf("Replayed %d pending webhook events", len(events))
+	}
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -68,10 +176,34 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Определяем тип вебхука и обрабатываем
-	if err := s.processWebhook(body); err != nil {
-		log.Printf("Error processing webhook: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	if !json.Valid(body) {
+		log.Printf("Invalid webhook payload: not a JSON document")
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	event := &models.WebhookEvent{
+		ID:   r.Header.Get("X-Discourse-Event-Id"),
+		Type: r.Header.Get("X-Discourse-Event-Type"),
+		Name: r.Header.Get("X-Discourse-Event"),
+		Body: body,
+	}
+
+	// Повторная доставка уже принятого события (Discourse ретраит медленные ответы)
+	if !s.claimEvent(event) {
+		log.Printf("Skipping duplicate webhook event %s (%s)", event.ID, event.Name)
+		metrics.Inc("duplicate_events")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+		return
+	}
+
+	// Ставим событие в очередь и сразу отвечаем, чтобы Discourse не ждал AI и Telegram
+	if !s.queue.Enqueue(event) {
+		s.releaseEvent(event)
+		log.Printf("Webhook queue is full, rejecting %s event %s", event.Name, event.ID)
+		metrics.Inc("queue_rejected")
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
 		return
 	}
 
@@ -79,6 +211,24 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
+// processEvent обрабатывает событие из очереди
+func (s *Server) processEvent(event *models.WebhookEvent) error {
+	// Передаем событие обработчику, зарегистрированному для его типа.
+	// Discourse уже получил ответ 200, поэтому при ошибке событие повторяем сами
+	if err := s.dispatcher.Dispatch(event); err != nil {
+		// Недоставленные сообщения уже в dead letters: повтор события отправил бы остальные чаты второй раз
+		if errors.Is(err, bot.ErrDeadLettered) {
+			log.Printf("Not retrying %s event %s: undelivered messages are in dead letters", event.Name, event.ID)
+			return err
+		}
+		if !s.retryEvent(event) {
+			s.releaseEvent(event)
+		}
+		return err
+	}
+	return nil
+}
+
 func (s *Server) verifyWebhookSignature(r *http.Request, body []byte) bool {
 	// Получаем подпись из заголовка
 	signature := r.Header.Get("X-Discourse-Event-Signature")
@@ -96,23 +246,25 @@ func (s *Server) verifyWebhookSignature(r *http.Request, body []byte) bool {
 	return hmac.Equal([]byte(signature), []byte(expectedSignature))
 }
 
-func (s *Server) processWebhook(body []byte) error {
-	// Пытаемся определить тип вебхука по содержимому
-	var topicWebhook models.WebhookTopic
-	var postWebhook models.WebhookPost
+func (s *Server) handlePing(event *models.WebhookEvent) error {
+	log.Printf("Received ping webhook (id: %s)", event.ID)
+	return nil
+}
 
-	// Сначала пробуем парсить как топик
-	if err := json.Unmarshal(body, &topicWebhook); err == nil && topicWebhook.Topic.ID != 0 {
-		return s.processTopic(&topicWebhook.Topic)
+func (s *Server) handleTopicCreated(event *models.WebhookEvent) error {
+	var topicWebhook models.WebhookTopic
+	if err := json.Unmarshal(event.Body, &topicWebhook); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %v", event.Name, err)
 	}
+	return s.processTopic(&topicWebhook.Topic)
+}
 
-	// Затем пробуем парсить как пост
-	if err := json.Unmarshal(body, &postWebhook); err == nil && postWebhook.Post.ID != 0 {
-		return s.processPost(&postWebhook.Post)
+func (s *Server) handlePostCreated(event *models.WebhookEvent) error {
+	var postWebhook models.WebhookPost
+	if err := json.Unmarshal(event.Body, &postWebhook); err != nil {
+		return fmt.Errorf("failed to decode %s payload: %v", event.Name, err)
 	}
-
-	log.Printf("Unknown webhook format: %s", string(body))
-	return fmt.Errorf("unknown webhook format")
+	return s.processPost(&postWebhook.Post)
 }
 
 func (s *Server) processTopic(topic *models.Topic) error {
@@ -124,8 +276,9 @@ func (s *Server) processTopic(topic *models.Topic) error {
 		return nil
 	}
 
-	// Проверяем, нужно ли отслеживать эту категорию
-	if !s.config.ShouldMonitorCategory(topic.CategoryID) {
+	// Проверяем, нужно ли отслеживать эту категорию. Тему из любой категории могут отправить
+	// правила наблюдения со своим чатом: совпадение проверяется, когда соберутся тема и пост
+	if !s.config.ShouldMonitorCategory(topic.CategoryID) && !s.config.HasWatchDestinations() {
 		log.Printf("Skipping topic %d - category %d is not monitored or is ignored", topic.ID, topic.CategoryID)
 		return nil
 	}
@@ -144,10 +297,9 @@ func (s *Server) processTopic(topic *models.Topic) error {
 func (s *Server) processPost(post *models.Post) error {
 	log.Printf("Processing post: %d in topic %d (Category: %d)", post.ID, post.TopicID, post.CategoryID)
 
-	// Обрабатываем только первый пост в теме (создание темы)
+	// Первый пост объединяется с темой, остальные - ответы
 	if post.PostNumber != 1 {
-		log.Printf("Skipping post %d - not the first post in topic", post.ID)
-		return nil
+		return s.processReply(post, post.AcceptedAnswer)
 	}
 
 	// Проверяем, нужно ли игнорировать этого пользователя
@@ -156,8 +308,9 @@ func (s *Server) processPost(post *models.Post) error {
 		return nil
 	}
 
-	// Проверяем, нужно ли отслеживать эту категорию
-	if !s.config.ShouldMonitorCategory(post.CategoryID) {
+	// Проверяем, нужно ли отслеживать эту категорию. Тему из любой категории могут отправить
+	// правила наблюдения со своим чатом: совпадение проверяется, когда соберутся тема и пост
+	if !s.config.ShouldMonitorCategory(post.CategoryID) && !s.config.HasWatchDestinations() {
 		log.Printf("Skipping post %d - category %d is not monitored or is ignored", post.ID, post.CategoryID)
 		return nil
 	}
@@ -173,6 +326,11 @@ func (s *Server) processPost(post *models.Post) error {
 	return nil
 }
 
+// topicURL формирует ссылку на тему
+func (s *Server) topicURL(slug string, topicID int) string {
+	return fmt.Sprintf("%s/t/%s/%d", s.config.BaseURL, slug, topicID)
+}
+
 func (s *Server) getCategoryName(data *storage.TopicData) string {
 	// Пытаемся получить имя категории из данных поста
 	if data.Post != nil && data.Post.CategorySlug != "" {
@@ -186,12 +344,8 @@ func (s *Server) getCategoryName(data *storage.TopicData) string {
 		return categoryName
 	}
 
-	// Если slug недоступен, показываем ID с более понятным форматом
-	if data.Topic != nil {
-		return fmt.Sprintf("Раздел %d", data.Topic.CategoryID)
-	}
-
-	return "Основной раздел"
+	// Если slug недоступен, название подставит бот на языке чата (см. categoryName)
+	return ""
 } // getUserRole определяет роль пользователя
 func (s *Server) getUserRole(user models.User, post *models.Post) string {
 	// Приоритет: данные из Post (более полные в webhook'ах)
@@ -232,31 +386,96 @@ func (s *Server) getUserRole(user models.User, post *models.Post) string {
 }
 
 func (s *Server) sendCompleteNotification(data *storage.TopicData) error {
-	// Определяем роль автора
-	authorRole := s.getUserRole(data.Topic.CreatedBy, data.Post)
-	log.Printf("Author: %s, Role: %s (Admin: %v, Moderator: %v, Staff: %v, TrustLevel: %d)",
-		data.Topic.CreatedBy.Username, authorRole,
-		data.Post.Admin, data.Post.Moderator, data.Post.Staff, data.Post.TrustLevel)
-
-	// Создаем объединенные данные для отправки
-	processed := &models.ProcessedWebhook{
-		Type:       "complete",
-		TopicID:    data.Topic.ID,
-		TopicTitle: data.Topic.Title,
-		Category:   s.getCategoryName(data),
-		CategoryID: data.Topic.CategoryID,
-		Author:     data.Topic.CreatedBy.Username,
-		AuthorRole: authorRole,
-		Content:    data.Post.Raw,
-		Tags:       data.Topic.Tags,
-		URL:        fmt.Sprintf("%s/t/%s/%d", s.config.BaseURL, data.Topic.Slug, data.Topic.ID),
+	metrics.Inc("delivery_complete")
+	return s.sendNotification(data)
+}
+
+func (s *Server) sendNotification(data *storage.TopicData) error {
+	processed := s.processedFromData(data)
+
+	// Тему из неотслеживаемой категории отправляем, только если она подошла под правило наблюдения со своим чатом
+	if !s.config.ShouldMonitorCategory(data.Topic.CategoryID) && !s.config.RoutedByWatch(processed) {
+		log.Printf("Skipping topic %d - category %d is not monitored and no watch rule matches", data.Topic.ID, data.Topic.CategoryID)
+		s.storage.RemoveTopic(data.Topic.ID)
+		return nil
+	}
+
+	// Тема уже анонсирована (повтор вебхука или гонка topic_created/post_created)
+	if !s.claimTopic(data.Topic.ID) {
+		log.Printf("Skipping topic %d - already announced", data.Topic.ID)
+		metrics.Inc("duplicate_topics")
+		s.storage.RemoveTopic(data.Topic.ID)
+		return nil
 	}
 
 	// Отправляем уведомление
 	err := s.bot.SendCompleteNotification(processed, s.config.IsPremiumCategory(data.Topic.CategoryID))
 
+	if err != nil {
+		if errors.Is(err, bot.ErrDeadLettered) {
+			// Анонсы сохранены в dead letters и отправляются оттуда; тема остается помеченной,
+			// чтобы повторная обработка не отправила их второй раз
+			s.storage.RemoveTopic(data.Topic.ID)
+			return err
+		}
+		// Тема и пост остаются в хранилище: повтор события снова соберет анонс
+		s.releaseTopic(data.Topic.ID)
+		return err
+	}
+
 	// Удаляем данные из хранилища после отправки
 	s.storage.RemoveTopic(data.Topic.ID)
+	return nil
+}
 
-	return err
+// processedFromData объединяет тему и первый пост в данные для анонса
+func (s *Server) processedFromData(data *storage.TopicData) *models.ProcessedWebhook {
+	// Определяем роль автора
+	authorRole := s.getUserRole(data.Topic.CreatedBy, data.Post)
+	log.Printf("Author: %s, Role: %s", data.Topic.CreatedBy.Username, authorRole)
+
+	// Содержимое первого поста; если его нет, используем выдержку из темы
+	content := data.Topic.Excerpt
+	if data.Post != nil {
+		content = data.Post.Raw
+	}
+	if content == "" {
+		content = data.Topic.Title
+	}
+
+	var categorySlug, imageURL string
+	if data.Post != nil {
+		categorySlug = data.Post.CategorySlug
+		imageURL = firstImageURL(data.Post.Cooked, s.config.BaseURL)
+	}
+
+	return &models.ProcessedWebhook{
+		Type:         "complete",
+		TopicID:      data.Topic.ID,
+		TopicTitle:   data.Topic.Title,
+		Category:     s.getCategoryName(data),
+		CategoryID:   data.Topic.CategoryID,
+		CategorySlug: categorySlug,
+		Author:       data.Topic.CreatedBy.Username,
+		AuthorRole:   authorRole,
+		Content:      content,
+		Tags:         data.Topic.Tags,
+		URL:          s.topicURL(data.Topic.Slug, data.Topic.ID),
+		ImageURL:     imageURL,
+	}
+}
+
+// loadTopic загружает первый пост темы через Discourse API для команды /resend
+func (s *Server) loadTopic(topicID int) (*models.ProcessedWebhook, bool, error) {
+	if !s.discourse.Enabled() {
+		return nil, false, fmt.Errorf("topic %d has not been announced and DISCOURSE_API_KEY is not set", topicID)
+	}
+
+	post, err := s.discourse.GetFirstPost(topicID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	data := &storage.TopicData{Topic: topicFromPost(post), Post: post}
+	return s.processedFromData(data), s.config.IsPremiumCategory(post.CategoryID), nil
 }
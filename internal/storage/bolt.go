@@ -0,0 +1,630 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+	"webhook_tg_bot/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	topicsBucket        = []byte("topics")
+	claimsBucket        = []byte("claims")
+	lettersBucket       = []byte("dead_letters")
+	pendingBucket       = []byte("pending_events")
+	announcementsBucket = []byte("announcements")
+	mutesBucket         = []byte("mutes")
+	subscriptionsBucket = []byte("subscriptions")
+)
+
+// BoltStorage хранилище на диске (bbolt), переживающее перезапуск контейнера
+type BoltStorage struct {
+	db              *bolt.DB
+	ttl             time.Duration
+	announcementTTL time.Duration // 0 - анонсы хранятся бессрочно
+	stop            chan struct{}
+	done            chan struct{}
+}
+
+// NewBoltStorage открывает (или создает) файл базы данных.
+// Анонсы удаляются через announcementTTL после последнего обновления
+func NewBoltStorage(path string, ttl, announcementTTL time.Duration) (*BoltStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{topicsBucket, claimsBucket, lettersBucket, pendingBucket, announcementsBucket, mutesBucket, subscriptionsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize bolt database: %v", err)
+	}
+
+	storage := &BoltStorage{
+		db:              db,
+		ttl:             ttl,
+		announcementTTL: announcementTTL,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+
+	// Запускаем горутину для очистки устаревших записей
+	go storage.cleanup()
+
+	return storage, nil
+}
+
+// AddTopic добавляет данные о теме
+func (s *BoltStorage) AddTopic(topic *models.Topic) {
+	err := s.updateTopic(topic.ID, func(data *TopicData) {
+		data.Topic = topic
+	})
+	if err != nil {
+		log.Printf("Failed to store topic %d: %v", topic.ID, err)
+	}
+}
+
+// AddPost добавляет данные о посте
+func (s *BoltStorage) AddPost(post *models.Post) {
+	err := s.updateTopic(post.TopicID, func(data *TopicData) {
+		data.Post = post
+	})
+	if err != nil {
+		log.Printf("Failed to store post %d for topic %d: %v", post.ID, post.TopicID, err)
+	}
+}
+
+// GetCompleteData возвращает полные данные о теме, если они есть
+func (s *BoltStorage) GetCompleteData(topicID int) (*TopicData, bool) {
+	var data *TopicData
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		data, err = getTopicData(tx.Bucket(topicsBucket), topicID)
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to read topic %d: %v", topicID, err)
+		return nil, false
+	}
+
+	if data == nil || !data.Complete {
+		return nil, false
+	}
+
+	return data, true
+}
+
+// RemoveTopic удаляет данные о теме
+func (s *BoltStorage) RemoveTopic(topicID int) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(topicsBucket).Delete(topicKey(topicID))
+	})
+	if err != nil {
+		log.Printf("Failed to remove topic %d: %v", topicID, err)
+	}
+}
+
+// TakeIncomplete извлекает неполные записи старше olderThan
+func (s *BoltStorage) TakeIncomplete(olderThan time.Duration) []*TopicData {
+	var result []*TopicData
+	now := time.Now()
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(topicsBucket)
+		var taken [][]byte
+
+		err := bucket.ForEach(func(k, v []byte) error {
+			var data TopicData
+			if err := json.Unmarshal(v, &data); err != nil {
+				return nil // битые записи удалит cleanup
+			}
+			if !data.Complete && now.Sub(data.CreatedAt) > olderThan {
+				result = append(result, &data)
+				taken = append(taken, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range taken {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to take incomplete topics: %v", err)
+		return nil
+	}
+
+	return result
+}
+
+// Claim атомарно помечает ключ как обработанный на время window
+func (s *BoltStorage) Claim(key string, window time.Duration) bool {
+	claimed := false
+	now := time.Now()
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(claimsBucket)
+		if expiresAt, ok := decodeExpiry(bucket.Get([]byte(key))); ok && now.Before(expiresAt) {
+			return nil
+		}
+
+		claimed = true
+		return bucket.Put([]byte(key), encodeExpiry(now.Add(window)))
+	})
+	if err != nil {
+		// Лучше отправить дубликат, чем потерять уведомление
+		log.Printf("Failed to claim %s: %v", key, err)
+		return true
+	}
+
+	return claimed
+}
+
+// Release снимает пометку с ключа
+func (s *BoltStorage) Release(key string) {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(claimsBucket).Delete([]byte(key))
+	})
+	if err != nil {
+		log.Printf("Failed to release %s: %v", key, err)
+	}
+}
+
+// AddDeadLetter сохраняет недоставленное сообщение
+func (s *BoltStorage) AddDeadLetter(letter *models.DeadLetter) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(lettersBucket), []byte(letter.ID), letter)
+	})
+}
+
+// ListDeadLetters возвращает недоставленные сообщения, начиная со старых
+func (s *BoltStorage) ListDeadLetters() ([]*models.DeadLetter, error) {
+	var result []*models.DeadLetter
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(lettersBucket).ForEach(func(k, v []byte) error {
+			var letter models.DeadLetter
+			if err := json.Unmarshal(v, &letter); err != nil {
+				return fmt.Errorf("failed to decode dead letter %s: %v", k, err)
+			}
+			result = append(result, &letter)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortDeadLetters(result)
+	return result, nil
+}
+
+// GetDeadLetter возвращает недоставленное сообщение по ID
+func (s *BoltStorage) GetDeadLetter(id string) (*models.DeadLetter, error) {
+	var letter *models.DeadLetter
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(lettersBucket).Get([]byte(id))
+		if raw == nil {
+			return nil
+		}
+		letter = &models.DeadLetter{}
+		return json.Unmarshal(raw, letter)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter %s: %v", id, err)
+	}
+
+	return letter, nil
+}
+
+// RemoveDeadLetter удаляет недоставленное сообщение
+func (s *BoltStorage) RemoveDeadLetter(id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(lettersBucket).Delete([]byte(id))
+	})
+}
+
+// updateTopic атомарно читает, изменяет и сохраняет данные о теме
+func (s *BoltStorage) updateTopic(topicID int, update func(data *TopicData)) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(topicsBucket)
+
+		data, err := getTopicData(bucket, topicID)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			data = &TopicData{
+				CreatedAt: time.Now(),
+			}
+		}
+
+		update(data)
+		data.Complete = data.Topic != nil && data.Post != nil
+
+		return putJSON(bucket, topicKey(topicID), data)
+	})
+}
+
+// SaveAnnouncement сохраняет опубликованный анонс темы
+func (s *BoltStorage) SaveAnnouncement(announcement *models.Announcement) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(announcementsBucket), topicKey(announcement.TopicID), announcement)
+	})
+}
+
+// GetAnnouncement возвращает анонс темы
+func (s *BoltStorage) GetAnnouncement(topicID int) (*models.Announcement, error) {
+	var announcement *models.Announcement
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(announcementsBucket).Get(topicKey(topicID))
+		if raw == nil {
+			return nil
+		}
+		announcement = &models.Announcement{}
+		return json.Unmarshal(raw, announcement)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read announcement for topic %d: %v", topicID, err)
+	}
+
+	return announcement, nil
+}
+
+// RemoveAnnouncement удаляет анонс темы
+func (s *BoltStorage) RemoveAnnouncement(topicID int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(announcementsBucket).Delete(topicKey(topicID))
+	})
+}
+
+// SaveMute сохраняет отключение категории
+func (s *BoltStorage) SaveMute(mute *models.Mute) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(mutesBucket), []byte(muteKey(mute.ChatID, mute.Category)), mute)
+	})
+}
+
+// ListMutes возвращает действующие отключения
+func (s *BoltStorage) ListMutes() ([]*models.Mute, error) {
+	var result []*models.Mute
+
+	now := time.Now()
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(mutesBucket).ForEach(func(k, v []byte) error {
+			var mute models.Mute
+			if err := json.Unmarshal(v, &mute); err != nil {
+				return fmt.Errorf("failed to decode mute %s: %v", k, err)
+			}
+			if mute.Active(now) {
+				result = append(result, &mute)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortMutes(result)
+	return result, nil
+}
+
+// RemoveMutes снимает отключения в чате
+func (s *BoltStorage) RemoveMutes(chatID int64, category string) (int, error) {
+	removed := 0
+	now := time.Now()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(mutesBucket)
+		var keys [][]byte
+
+		err := bucket.ForEach(func(k, v []byte) error {
+			var mute models.Mute
+			if err := json.Unmarshal(v, &mute); err != nil {
+				return fmt.Errorf("failed to decode mute %s: %v", k, err)
+			}
+			if mute.ChatID == chatID && (category == "" || mute.Category == category) {
+				keys = append(keys, append([]byte(nil), k...))
+				if mute.Active(now) {
+					removed++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove mutes: %v", err)
+	}
+	return removed, nil
+}
+
+// SaveSubscription сохраняет личную подписку пользователя
+func (s *BoltStorage) SaveSubscription(subscription *models.Subscription) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(subscriptionsBucket), userKey(subscription.UserID), subscription)
+	})
+}
+
+// GetSubscription возвращает подписку пользователя
+func (s *BoltStorage) GetSubscription(userID int64) (*models.Subscription, error) {
+	var subscription *models.Subscription
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(subscriptionsBucket).Get(userKey(userID))
+		if raw == nil {
+			return nil
+		}
+		subscription = &models.Subscription{}
+		return json.Unmarshal(raw, subscription)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read subscription of user %d: %v", userID, err)
+	}
+
+	return subscription, nil
+}
+
+// ListSubscriptions возвращает все подписки
+func (s *BoltStorage) ListSubscriptions() ([]*models.Subscription, error) {
+	var result []*models.Subscription
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(subscriptionsBucket).ForEach(func(k, v []byte) error {
+			var subscription models.Subscription
+			if err := json.Unmarshal(v, &subscription); err != nil {
+				return fmt.Errorf("failed to decode subscription %s: %v", k, err)
+			}
+			result = append(result, &subscription)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RemoveSubscription удаляет подписку пользователя
+func (s *BoltStorage) RemoveSubscription(userID int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(subscriptionsBucket).Delete(userKey(userID))
+	})
+}
+
+// SavePendingEvents сохраняет события, которые не успели обработать до остановки
+func (s *BoltStorage) SavePendingEvents(events []*models.WebhookEvent) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(pendingBucket)
+		for _, event := range events {
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			if err := putJSON(bucket, []byte(fmt.Sprintf("%020d", seq)), event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// TakePendingEvents извлекает сохраненные при остановке события
+func (s *BoltStorage) TakePendingEvents() ([]*models.WebhookEvent, error) {
+	var events []*models.WebhookEvent
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(pendingBucket)
+		err := bucket.ForEach(func(k, v []byte) error {
+			var event models.WebhookEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				log.Printf("Skipping corrupted pending event %s: %v", k, err)
+				return nil
+			}
+			events = append(events, &event)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := tx.DeleteBucket(pendingBucket); err != nil {
+			return err
+		}
+		_, err = tx.CreateBucket(pendingBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pending events: %v", err)
+	}
+
+	return events, nil
+}
+
+// Persistent: база на диске переживает перезапуск
+func (s *BoltStorage) Persistent() bool {
+	return true
+}
+
+// Close останавливает фоновую очистку и закрывает базу
+func (s *BoltStorage) Close() error {
+	close(s.stop)
+	<-s.done
+	return s.db.Close()
+}
+
+// cleanup удаляет устаревшие записи
+func (s *BoltStorage) cleanup() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.removeExpired()
+		}
+	}
+}
+
+func (s *BoltStorage) removeExpired() {
+	now := time.Now()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(topicsBucket)
+		var expired [][]byte
+
+		err := bucket.ForEach(func(k, v []byte) error {
+			var data TopicData
+			if err := json.Unmarshal(v, &data); err != nil || now.Sub(data.CreatedAt) > s.ttl {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		if err := deleteExpiredClaims(tx.Bucket(claimsBucket), now); err != nil {
+			return err
+		}
+		if s.announcementTTL == 0 {
+			return nil
+		}
+		return deleteExpiredAnnouncements(tx.Bucket(announcementsBucket), now.Add(-s.announcementTTL))
+	})
+	if err != nil {
+		log.Printf("Failed to clean up bolt storage: %v", err)
+	}
+}
+
+func deleteExpiredClaims(bucket *bolt.Bucket, now time.Time) error {
+	var expired [][]byte
+
+	err := bucket.ForEach(func(k, v []byte) error {
+		if expiresAt, ok := decodeExpiry(v); !ok || now.After(expiresAt) {
+			expired = append(expired, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range expired {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteExpiredAnnouncements удаляет анонсы, которые не обновлялись с before
+func deleteExpiredAnnouncements(bucket *bolt.Bucket, before time.Time) error {
+	var expired [][]byte
+
+	err := bucket.ForEach(func(k, v []byte) error {
+		var announcement models.Announcement
+		if err := json.Unmarshal(v, &announcement); err != nil || announcement.UpdatedAt.Before(before) {
+			expired = append(expired, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range expired {
+		if err := bucket.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeExpiry(t time.Time) []byte {
+	return []byte(strconv.FormatInt(t.UnixNano(), 10))
+}
+
+func decodeExpiry(raw []byte) (time.Time, bool) {
+	if raw == nil {
+		return time.Time{}, false
+	}
+	nanos, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+func topicKey(topicID int) []byte {
+	return []byte(strconv.Itoa(topicID))
+}
+
+func userKey(userID int64) []byte {
+	return []byte(strconv.FormatInt(userID, 10))
+}
+
+func getTopicData(bucket *bolt.Bucket, topicID int) (*TopicData, error) {
+	raw := bucket.Get(topicKey(topicID))
+	if raw == nil {
+		return nil, nil
+	}
+
+	var data TopicData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode topic %d: %v", topicID, err)
+	}
+	return &data, nil
+}
+
+func putJSON(bucket *bolt.Bucket, key []byte, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, raw)
+}
@@ -1,31 +1,128 @@
 package storage
 
 import (
+	"fmt"
+	"slices"
+	"sort"
 	"sync"
 	"time"
+	"webhook_tg_bot/internal/config"
 	"webhook_tg_bot/internal/models"
 )
 
 // TopicData объединенные данные о теме
 type TopicData struct {
-	Topic     *models.Topic
-	Post      *models.Post
-	CreatedAt time.Time
-	Complete  bool // есть ли и топик и пост
+	Topic     *models.Topic `json:"topic,omitempty"`
+	Post      *models.Post  `json:"post,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	Complete  bool          `json:"complete"` // есть ли и топик и пост
+}
+
+// Storage буфер для объединения вебхуков темы и первого поста
+type Storage interface {
+	// AddTopic добавляет данные о теме
+	AddTopic(topic *models.Topic)
+	// AddPost добавляет данные о посте
+	AddPost(post *models.Post)
+	// GetCompleteData возвращает полные данные о теме, если они есть
+	GetCompleteData(topicID int) (*TopicData, bool)
+	// RemoveTopic удаляет данные о теме
+	RemoveTopic(topicID int)
+	// TakeIncomplete извлекает неполные записи старше olderThan
+	TakeIncomplete(olderThan time.Duration) []*TopicData
+
+	// Claim атомарно помечает ключ как обработанный на время window.
+	// Возвращает false, если ключ уже был помечен и окно еще не истекло
+	Claim(key string, window time.Duration) bool
+	// Release снимает пометку, например если обработка завершилась ошибкой
+	Release(key string)
+
+	// AddDeadLetter сохраняет недоставленное сообщение
+	AddDeadLetter(letter *models.DeadLetter) error
+	// ListDeadLetters возвращает недоставленные сообщения, начиная со старых
+	ListDeadLetters() ([]*models.DeadLetter, error)
+	// GetDeadLetter возвращает недоставленное сообщение по ID (nil, если его нет)
+	GetDeadLetter(id string) (*models.DeadLetter, error)
+	// RemoveDeadLetter удаляет недоставленное сообщение
+	RemoveDeadLetter(id string) error
+
+	// SaveAnnouncement сохраняет опубликованный анонс темы
+	SaveAnnouncement(announcement *models.Announcement) error
+	// GetAnnouncement возвращает анонс темы (nil, если тема не анонсировалась)
+	GetAnnouncement(topicID int) (*models.Announcement, error)
+	// RemoveAnnouncement удаляет анонс темы
+	RemoveAnnouncement(topicID int) error
+
+	// SaveMute сохраняет отключение категории (повторное отключение той же категории в том же чате заменяет прежнее)
+	SaveMute(mute *models.Mute) error
+	// ListMutes возвращает действующие отключения
+	ListMutes() ([]*models.Mute, error)
+	// RemoveMutes снимает отключения в чате chatID (0 - общие) для категории, а при пустой category - все.
+	// Возвращает количество снятых отключений
+	RemoveMutes(chatID int64, category string) (int, error)
+
+	// SaveSubscription сохраняет личную подписку пользователя
+	SaveSubscription(subscription *models.Subscription) error
+	// GetSubscription возвращает подписку пользователя (nil, если ее нет)
+	GetSubscription(userID int64) (*models.Subscription, error)
+	// ListSubscriptions возвращает все подписки
+	ListSubscriptions() ([]*models.Subscription, error)
+	// RemoveSubscription удаляет подписку пользователя
+	RemoveSubscription(userID int64) error
+
+	// SavePendingEvents сохраняет события, которые не успели обработать до остановки
+	SavePendingEvents(events []*models.WebhookEvent) error
+	// TakePendingEvents извлекает сохраненные при остановке события
+	TakePendingEvents() ([]*models.WebhookEvent, error)
+
+	// Persistent сообщает, переживают ли данные перезапуск
+	Persistent() bool
+
+	// Close останавливает фоновую очистку и освобождает ресурсы
+	Close() error
+}
+
+// New создает хранилище, выбранное в конфигурации
+func New(cfg *config.Config) (Storage, error) {
+	switch cfg.StorageType {
+	case "", "memory":
+		return NewMemoryStorage(cfg.TopicTTL, cfg.AnnouncementTTL), nil
+	case "bolt":
+		return NewBoltStorage(cfg.StoragePath, cfg.TopicTTL, cfg.AnnouncementTTL)
+	default:
+		return nil, fmt.Errorf("unknown storage type: %s", cfg.StorageType)
+	}
 }
 
 // MemoryStorage простое хранилище в памяти
 type MemoryStorage struct {
-	topics map[int]*TopicData
-	mutex  sync.RWMutex
-	ttl    time.Duration
+	topics          map[int]*TopicData
+	claims          map[string]time.Time // ключ -> время истечения пометки
+	letters         map[string]*models.DeadLetter
+	announcements   map[int]*models.Announcement
+	mutes           map[string]*models.Mute
+	subscriptions   map[int64]*models.Subscription
+	pending         []*models.WebhookEvent
+	mutex           sync.RWMutex
+	ttl             time.Duration
+	announcementTTL time.Duration // 0 - анонсы хранятся бессрочно
+	stop            chan struct{}
+	done            chan struct{}
 }
 
-// NewMemoryStorage создает новое хранилище
-func NewMemoryStorage() *MemoryStorage {
+// NewMemoryStorage создает новое хранилище. Анонсы удаляются через announcementTTL после последнего обновления
+func NewMemoryStorage(ttl, announcementTTL time.Duration) *MemoryStorage {
 	storage := &MemoryStorage{
-		topics: make(map[int]*TopicData),
-		ttl:    5 * time.Minute, // TTL для автоочистки
+		topics:          make(map[int]*TopicData),
+		claims:          make(map[string]time.Time),
+		letters:         make(map[string]*models.DeadLetter),
+		announcements:   make(map[int]*models.Announcement),
+		mutes:           make(map[string]*models.Mute),
+		subscriptions:   make(map[int64]*models.Subscription),
+		ttl:             ttl, // TTL для автоочистки
+		announcementTTL: announcementTTL,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
 	}
 
 	// Запускаем горутину для очистки устаревших записей
@@ -89,19 +186,288 @@ func (s *MemoryStorage) RemoveTopic(topicID int) {
 	delete(s.topics, topicID)
 }
 
+// TakeIncomplete извлекает неполные записи старше olderThan
+func (s *MemoryStorage) TakeIncomplete(olderThan time.Duration) []*TopicData {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var result []*TopicData
+	now := time.Now()
+	for topicID, data := range s.topics {
+		if !data.Complete && now.Sub(data.CreatedAt) > olderThan {
+			result = append(result, data)
+			delete(s.topics, topicID)
+		}
+	}
+	return result
+}
+
+// Claim атомарно помечает ключ как обработанный на время window
+func (s *MemoryStorage) Claim(key string, window time.Duration) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if expiresAt, exists := s.claims[key]; exists && now.Before(expiresAt) {
+		return false
+	}
+
+	s.claims[key] = now.Add(window)
+	return true
+}
+
+// Release снимает пометку с ключа
+func (s *MemoryStorage) Release(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.claims, key)
+}
+
+// AddDeadLetter сохраняет недоставленное сообщение
+func (s *MemoryStorage) AddDeadLetter(letter *models.DeadLetter) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.letters[letter.ID] = letter
+	return nil
+}
+
+// ListDeadLetters возвращает недоставленные сообщения, начиная со старых
+func (s *MemoryStorage) ListDeadLetters() ([]*models.DeadLetter, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]*models.DeadLetter, 0, len(s.letters))
+	for _, letter := range s.letters {
+		result = append(result, letter)
+	}
+	sortDeadLetters(result)
+	return result, nil
+}
+
+// GetDeadLetter возвращает недоставленное сообщение по ID
+func (s *MemoryStorage) GetDeadLetter(id string) (*models.DeadLetter, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.letters[id], nil
+}
+
+// RemoveDeadLetter удаляет недоставленное сообщение
+func (s *MemoryStorage) RemoveDeadLetter(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.letters, id)
+	return nil
+}
+
+func sortDeadLetters(letters []*models.DeadLetter) {
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].CreatedAt.Before(letters[j].CreatedAt)
+	})
+}
+
+// SaveAnnouncement сохраняет опубликованный анонс темы
+func (s *MemoryStorage) SaveAnnouncement(announcement *models.Announcement) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.announcements[announcement.TopicID] = announcement
+	return nil
+}
+
+// GetAnnouncement возвращает анонс темы
+func (s *MemoryStorage) GetAnnouncement(topicID int) (*models.Announcement, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.announcements[topicID], nil
+}
+
+// RemoveAnnouncement удаляет анонс темы
+func (s *MemoryStorage) RemoveAnnouncement(topicID int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.announcements, topicID)
+	return nil
+}
+
+// SaveMute сохраняет отключение категории
+func (s *MemoryStorage) SaveMute(mute *models.Mute) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.mutes[muteKey(mute.ChatID, mute.Category)] = mute
+	return nil
+}
+
+// ListMutes возвращает действующие отключения
+func (s *MemoryStorage) ListMutes() ([]*models.Mute, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	now := time.Now()
+	result := make([]*models.Mute, 0, len(s.mutes))
+	for _, mute := range s.mutes {
+		if mute.Active(now) {
+			result = append(result, mute)
+		}
+	}
+	sortMutes(result)
+	return result, nil
+}
+
+// RemoveMutes снимает отключения в чате
+func (s *MemoryStorage) RemoveMutes(chatID int64, category string) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	removed := 0
+	for key, mute := range s.mutes {
+		if mute.ChatID == chatID && (category == "" || mute.Category == category) {
+			delete(s.mutes, key)
+			if mute.Active(time.Now()) {
+				removed++
+			}
+		}
+	}
+	return removed, nil
+}
+
+// muteKey ключ отключения: чат и категория
+func muteKey(chatID int64, category string) string {
+	return fmt.Sprintf("%d/%s", chatID, category)
+}
+
+func sortMutes(mutes []*models.Mute) {
+	sort.Slice(mutes, func(i, j int) bool {
+		return mutes[i].CreatedAt.Before(mutes[j].CreatedAt)
+	})
+}
+
+// SaveSubscription сохраняет личную подписку пользователя
+func (s *MemoryStorage) SaveSubscription(subscription *models.Subscription) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.subscriptions[subscription.UserID] = cloneSubscription(subscription)
+	return nil
+}
+
+// GetSubscription возвращает подписку пользователя
+func (s *MemoryStorage) GetSubscription(userID int64) (*models.Subscription, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	subscription, exists := s.subscriptions[userID]
+	if !exists {
+		return nil, nil
+	}
+	// Копия, чтобы изменения до SaveSubscription не были видны другим горутинам
+	return cloneSubscription(subscription), nil
+}
+
+// ListSubscriptions возвращает все подписки
+func (s *MemoryStorage) ListSubscriptions() ([]*models.Subscription, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make([]*models.Subscription, 0, len(s.subscriptions))
+	for _, subscription := range s.subscriptions {
+		result = append(result, cloneSubscription(subscription))
+	}
+	return result, nil
+}
+
+// RemoveSubscription удаляет подписку пользователя
+func (s *MemoryStorage) RemoveSubscription(userID int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.subscriptions, userID)
+	return nil
+}
+
+func cloneSubscription(subscription *models.Subscription) *models.Subscription {
+	copied := *subscription
+	copied.Categories = slices.Clone(subscription.Categories)
+	copied.Tags = slices.Clone(subscription.Tags)
+	copied.Authors = slices.Clone(subscription.Authors)
+	copied.Keywords = slices.Clone(subscription.Keywords)
+	return &copied
+}
+
+// SavePendingEvents запоминает события только до завершения процесса
+func (s *MemoryStorage) SavePendingEvents(events []*models.WebhookEvent) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.pending = append(s.pending, events...)
+	return nil
+}
+
+// TakePendingEvents извлекает сохраненные при остановке события
+func (s *MemoryStorage) TakePendingEvents() ([]*models.WebhookEvent, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	events := s.pending
+	s.pending = nil
+	return events, nil
+}
+
+// Persistent: данные в памяти теряются при остановке
+func (s *MemoryStorage) Persistent() bool {
+	return false
+}
+
+// Close останавливает фоновую очистку
+func (s *MemoryStorage) Close() error {
+	close(s.stop)
+	<-s.done
+	return nil
+}
+
 // cleanup удаляет устаревшие записи
 func (s *MemoryStorage) cleanup() {
+	defer close(s.done)
+
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		s.mutex.Lock()
-		now := time.Now()
-		for topicID, data := range s.topics {
-			if now.Sub(data.CreatedAt) > s.ttl {
-				delete(s.topics, topicID)
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.removeExpired()
+		}
+	}
+}
+
+func (s *MemoryStorage) removeExpired() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for topicID, data := range s.topics {
+		if now.Sub(data.CreatedAt) > s.ttl {
+			delete(s.topics, topicID)
+		}
+	}
+	for key, expiresAt := range s.claims {
+		if now.After(expiresAt) {
+			delete(s.claims, key)
+		}
+	}
+	if s.announcementTTL > 0 {
+		for topicID, announcement := range s.announcements {
+			if now.Sub(announcement.UpdatedAt) > s.announcementTTL {
+				delete(s.announcements, topicID)
 			}
 		}
-		s.mutex.Unlock()
 	}
 }
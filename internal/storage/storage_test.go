@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+	"webhook_tg_bot/internal/models"
+)
+
+// expiringStorage хранилище с ручным вызовом очистки
+type expiringStorage interface {
+	Storage
+	removeExpired()
+}
+
+func TestRemoveExpiredAnnouncements(t *testing.T) {
+	bolt, err := NewBoltStorage(filepath.Join(t.TempDir(), "bot.db"), time.Minute, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storages := map[string]expiringStorage{
+		"memory": NewMemoryStorage(time.Minute, time.Hour),
+		"bolt":   bolt,
+	}
+	for name, store := range storages {
+		t.Run(name, func(t *testing.T) {
+			defer store.Close()
+
+			now := time.Now()
+			store.SaveAnnouncement(&models.Announcement{TopicID: 1, UpdatedAt: now.Add(-2 * time.Hour)})
+			store.SaveAnnouncement(&models.Announcement{TopicID: 2, CreatedAt: now.Add(-2 * time.Hour), UpdatedAt: now})
+
+			store.removeExpired()
+
+			if announcement, _ := store.GetAnnouncement(1); announcement != nil {
+				t.Error("announcement not updated for longer than ANNOUNCEMENT_TTL was kept")
+			}
+			if announcement, _ := store.GetAnnouncement(2); announcement == nil {
+				t.Error("recently updated announcement was removed")
+			}
+		})
+	}
+}
+
+func TestAnnouncementsKeptWithoutTTL(t *testing.T) {
+	store := NewMemoryStorage(time.Minute, 0)
+	defer store.Close()
+
+	store.SaveAnnouncement(&models.Announcement{TopicID: 1, UpdatedAt: time.Now().Add(-365 * 24 * time.Hour)})
+	store.removeExpired()
+
+	if announcement, _ := store.GetAnnouncement(1); announcement == nil {
+		t.Error("announcement was removed with ANNOUNCEMENT_TTL=0")
+	}
+}
+
+func TestTakeIncomplete(t *testing.T) {
+	bolt, err := NewBoltStorage(filepath.Join(t.TempDir(), "bot.db"), time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storages := map[string]Storage{
+		"memory": NewMemoryStorage(time.Hour, 0),
+		"bolt":   bolt,
+	}
+	for name, store := range storages {
+		t.Run(name, func(t *testing.T) {
+			defer store.Close()
+
+			store.AddTopic(&models.Topic{ID: 1})
+			store.AddPost(&models.Post{TopicID: 2})
+			store.AddTopic(&models.Topic{ID: 3})
+			store.AddPost(&models.Post{TopicID: 3})
+
+			if taken := store.TakeIncomplete(time.Hour); len(taken) != 0 {
+				t.Fatalf("TakeIncomplete(1h) took %d records before the grace period ended", len(taken))
+			}
+
+			time.Sleep(5 * time.Millisecond)
+			taken := store.TakeIncomplete(time.Millisecond)
+			got := map[int]bool{}
+			for _, data := range taken {
+				switch {
+				case data.Topic != nil:
+					got[data.Topic.ID] = true
+				case data.Post != nil:
+					got[data.Post.TopicID] = true
+				}
+			}
+			if len(taken) != 2 || !got[1] || !got[2] {
+				t.Errorf("TakeIncomplete() took topics %v, want 1 and 2", got)
+			}
+
+			// Взятые записи удаляются, полные остаются ждать отправки
+			if taken := store.TakeIncomplete(0); len(taken) != 0 {
+				t.Errorf("TakeIncomplete() returned %d records a second time", len(taken))
+			}
+			if _, ok := store.GetCompleteData(3); !ok {
+				t.Error("complete topic was taken as incomplete")
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
@@ -9,30 +11,44 @@ import (
 	"webhook_tg_bot/internal/bot"
 	"webhook_tg_bot/internal/config"
 	"webhook_tg_bot/internal/server"
+	"webhook_tg_bot/internal/storage"
 
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	configFile := flag.String("config", "", "path to YAML config file (overrides CONFIG_FILE)")
+	flag.Parse()
+
 	// Загружаем переменные окружения из .env файла
 	if err := godotenv.Load(); err != nil {
 		log.Printf("Warning: .env file not found, using system environment variables")
 	}
 
 	// Загружаем конфигурацию
-	cfg, err := config.Load()
+	if *configFile == "" {
+		*configFile = os.Getenv("CONFIG_FILE")
+	}
+	cfg, err := config.Load(*configFile)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Инициализируем хранилище для объединения вебхуков
+	store, err := storage.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create storage: %v", err)
+	}
+	log.Printf("Using %s storage", cfg.StorageType)
+
 	// Инициализируем Telegram бота
-	telegramBot, err := bot.New(cfg)
+	telegramBot, err := bot.New(cfg, store)
 	if err != nil {
 		log.Fatalf("Failed to create telegram bot: %v", err)
 	}
 
 	// Инициализируем веб-сервер для вебхуков
-	webhookServer := server.New(cfg, telegramBot)
+	webhookServer := server.New(cfg, telegramBot, store)
 
 	// Запускаем сервер в отдельной горутине
 	go func() {
@@ -48,4 +64,18 @@ func main() {
 	<-c
 
 	log.Println("Shutting down...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// Прекращаем прием вебхуков и дожидаемся отправки уведомлений
+	if err := webhookServer.Shutdown(ctx); err != nil {
+		log.Printf("Error during shutdown: %v", err)
+	}
+
+	if err := store.Close(); err != nil {
+		log.Printf("Error closing storage: %v", err)
+	}
+
+	log.Println("Shutdown complete")
 }